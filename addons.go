@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	ekscontrolplanev2 "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+)
+
+// addonResolutions maps the EKS conflict resolution settings onto CAPA.
+// CAPA has no equivalent of PRESERVE, which is mapped to none so that CAPA
+// never overwrites settings changed on the cluster.
+var addonResolutions map[types.ResolveConflicts]ekscontrolplanev2.AddonResolution = map[types.ResolveConflicts]ekscontrolplanev2.AddonResolution{
+	types.ResolveConflictsOverwrite: ekscontrolplanev2.AddonResolutionOverwrite,
+	types.ResolveConflictsNone:      ekscontrolplanev2.AddonResolutionNone,
+	types.ResolveConflictsPreserve:  ekscontrolplanev2.AddonResolutionNone,
+}
+
+// maxAddonUpdates caps the updates described per add-on, as EKS offers no
+// way to filter the update history by parameter
+const maxAddonUpdates = 20
+
+// getAddons reads the EKS managed add-ons installed on the cluster
+//
+// Add-ons which cannot be read are left out and reported as warnings.
+func getAddons(cluster string, client AwsEksApi) (addons *[]ekscontrolplanev2.Addon, warnings []error) {
+	var (
+		input *eks.ListAddonsInput = &eks.ListAddonsInput{
+			ClusterName: &cluster,
+		}
+		names []string
+	)
+
+	for {
+		res, err := ListAddons(context.TODO(), client, input)
+		if err != nil {
+			return nil, append(warnings, errors.Wrap(err, "cannot list add-ons"))
+		}
+
+		names = append(names, res.Addons...)
+		if res.NextToken == nil {
+			break
+		}
+		input.NextToken = res.NextToken
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var list []ekscontrolplanev2.Addon
+	for _, name := range names {
+		res, err := DescribeAddon(context.TODO(), client, &eks.DescribeAddonInput{
+			ClusterName: &cluster,
+			AddonName:   aws.String(name),
+		})
+		if err != nil {
+			warnings = append(warnings, errors.Wrapf(err, "cannot describe add-on %q", name))
+			continue
+		}
+
+		if res.Addon == nil {
+			continue
+		}
+
+		var addon ekscontrolplanev2.Addon = ekscontrolplanev2.Addon{
+			Name:                  name,
+			Version:               aws.ToString(res.Addon.AddonVersion),
+			Configuration:         aws.ToString(res.Addon.ConfigurationValues),
+			ServiceAccountRoleArn: res.Addon.ServiceAccountRoleArn,
+		}
+
+		var resolution types.ResolveConflicts
+		if resolution, err = addonConflictResolution(cluster, name, client); err != nil {
+			warnings = append(warnings, errors.Wrapf(err, "cannot read conflict resolution of add-on %q", name))
+		}
+
+		if resolution == types.ResolveConflictsPreserve {
+			warnings = append(warnings, fmt.Errorf("add-on %q preserves conflicting settings which CAPA cannot do, conflicts will not be resolved", name))
+		}
+
+		// A setting which was never observed is left to CAPA rather than
+		// guessed
+		if mapped, ok := addonResolutions[resolution]; ok {
+			addon.ConflictResolution = &mapped
+		} else if err == nil {
+			warnings = append(warnings, fmt.Errorf("add-on %q has no recorded conflict resolution, set one with a patch such as none to keep CAPA from applying its default", name))
+		}
+
+		list = append(list, addon)
+	}
+	return &list, warnings
+}
+
+// addonConflictResolution finds the conflict resolution used by the most
+// recent create or update request of the add-on. EKS does not return the
+// setting when describing an add-on so it is only known from this history.
+//
+// At most maxAddonUpdates updates are described. The resolution is empty if
+// none of them set it.
+func addonConflictResolution(cluster, addon string, client AwsEksApi) (resolution types.ResolveConflicts, err error) {
+	var (
+		input *eks.ListUpdatesInput = &eks.ListUpdatesInput{
+			Name:      &cluster,
+			AddonName: &addon,
+		}
+		latest    *types.Update
+		described int
+	)
+
+history:
+	for {
+		var res *eks.ListUpdatesOutput
+		if res, err = ListUpdates(context.TODO(), client, input); err != nil {
+			return "", err
+		}
+
+		for _, id := range res.UpdateIds {
+			if described == maxAddonUpdates {
+				break history
+			}
+			described++
+
+			var update *eks.DescribeUpdateOutput
+			if update, err = DescribeUpdate(context.TODO(), client, &eks.DescribeUpdateInput{
+				Name:      &cluster,
+				AddonName: &addon,
+				UpdateId:  aws.String(id),
+			}); err != nil {
+				return "", err
+			}
+
+			if update.Update == nil || !hasUpdateParam(update.Update, types.UpdateParamTypeResolveConflicts) {
+				continue
+			}
+
+			if latest == nil || aws.ToTime(update.Update.CreatedAt).After(aws.ToTime(latest.CreatedAt)) {
+				latest = update.Update
+			}
+		}
+
+		if res.NextToken == nil {
+			break
+		}
+		input.NextToken = res.NextToken
+	}
+
+	if latest == nil {
+		return "", nil
+	}
+
+	for _, param := range latest.Params {
+		if param.Type == types.UpdateParamTypeResolveConflicts {
+			resolution = types.ResolveConflicts(aws.ToString(param.Value))
+		}
+	}
+	return resolution, nil
+}
+
+// hasUpdateParam returns true if the update changed the given parameter
+func hasUpdateParam(update *types.Update, param types.UpdateParamType) bool {
+	for _, p := range update.Params {
+		if p.Type == param {
+			return true
+		}
+	}
+	return false
+}
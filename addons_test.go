@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// UpdateHistoryMock lists a long update history in which only the oldest
+// and the newest updates set the conflict resolution
+type UpdateHistoryMock struct {
+	EmptyNodegroupMock
+	updates   int
+	described int
+}
+
+func (n *UpdateHistoryMock) ListUpdates(ctx context.Context,
+	params *eks.ListUpdatesInput,
+	optFns ...func(*eks.Options)) (*eks.ListUpdatesOutput, error) {
+	var ids []string
+	for i := n.updates; i > 0; i-- {
+		ids = append(ids, fmt.Sprintf("u-%d", i))
+	}
+	return &eks.ListUpdatesOutput{UpdateIds: ids}, nil
+}
+
+func (n *UpdateHistoryMock) DescribeUpdate(ctx context.Context,
+	params *eks.DescribeUpdateInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeUpdateOutput, error) {
+	n.described++
+
+	var number int
+	_, _ = fmt.Sscanf(*params.UpdateId, "u-%d", &number)
+
+	var update *types.Update = &types.Update{
+		Id:        params.UpdateId,
+		CreatedAt: aws.Time(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, number)),
+		Params:    []types.UpdateParam{{Type: types.UpdateParamTypeAddonVersion, Value: aws.String("latest")}},
+	}
+
+	var resolution types.ResolveConflicts
+	switch number {
+	case 1:
+		resolution = types.ResolveConflictsOverwrite
+	case n.updates:
+		resolution = types.ResolveConflictsNone
+	}
+
+	if resolution != "" {
+		update.Params = append(update.Params, types.UpdateParam{
+			Type:  types.UpdateParamTypeResolveConflicts,
+			Value: aws.String(string(resolution)),
+		})
+	}
+	return &eks.DescribeUpdateOutput{Update: update}, nil
+}
+
+func TestAddonConflictResolution(t *testing.T) {
+	cases := map[string]struct {
+		updates       int
+		want          types.ResolveConflicts
+		wantDescribed int
+	}{
+		"no updates": {},
+		"newest update wins": {
+			updates:       3,
+			want:          types.ResolveConflictsNone,
+			wantDescribed: 3,
+		},
+		"long history is capped": {
+			updates:       maxAddonUpdates * 2,
+			want:          types.ResolveConflictsNone,
+			wantDescribed: maxAddonUpdates,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var client *UpdateHistoryMock = &UpdateHistoryMock{updates: tc.updates}
+			got, err := addonConflictResolution("test", "vpc-cni", client)
+			if err != nil {
+				t.Fatalf("addonConflictResolution(...): unexpected error: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("addonConflictResolution(...): want %q, got %q", tc.want, got)
+			}
+
+			if client.described != tc.wantDescribed {
+				t.Errorf("addonConflictResolution(...): want %d updates described, got %d", tc.wantDescribed, client.described)
+			}
+		})
+	}
+}
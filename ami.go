@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+const (
+	// amiFamilyAnnotation records the family of the AMI used by the launch template
+	amiFamilyAnnotation = "describenodegroups.fn.giantswarm.io/ami-family"
+
+	// amiIDAnnotation records the AMI ID replaced by an EKS optimized lookup
+	amiIDAnnotation = "describenodegroups.fn.giantswarm.io/ami-id"
+
+	// amiPinnedAnnotation marks pools which are pinned to a custom AMI
+	amiPinnedAnnotation = "describenodegroups.fn.giantswarm.io/ami-pinned"
+
+	// amiTypeAnnotation records the nodegroup AMI type when CAPA cannot hold it
+	amiTypeAnnotation = "describenodegroups.fn.giantswarm.io/ami-type"
+
+	// eksAMIOwner is the account publishing the EKS optimized AMIs
+	eksAMIOwner = "602401143452"
+)
+
+// amiFamily is the operating system family of an AMI
+type amiFamily string
+
+const (
+	amiFamilyAmazonLinux2    amiFamily = "AmazonLinux2"
+	amiFamilyAmazonLinux2023 amiFamily = "AmazonLinux2023"
+	amiFamilyBottlerocket    amiFamily = "Bottlerocket"
+	amiFamilyWindows         amiFamily = "Windows"
+	amiFamilyCustom          amiFamily = "Custom"
+)
+
+// amiTypeFamilies maps every EKS AMI type onto its family. The family of
+// CUSTOM is taken from the image itself.
+var amiTypeFamilies map[types.AMITypes]amiFamily = map[types.AMITypes]amiFamily{
+	types.AMITypesAl2X8664:                amiFamilyAmazonLinux2,
+	types.AMITypesAl2X8664Gpu:             amiFamilyAmazonLinux2,
+	types.AMITypesAl2Arm64:                amiFamilyAmazonLinux2,
+	types.AMITypesAl2023X8664Standard:     amiFamilyAmazonLinux2023,
+	types.AMITypesAl2023Arm64Standard:     amiFamilyAmazonLinux2023,
+	types.AMITypesAl2023X8664Neuron:       amiFamilyAmazonLinux2023,
+	types.AMITypesAl2023X8664Nvidia:       amiFamilyAmazonLinux2023,
+	types.AMITypesAl2023Arm64Nvidia:       amiFamilyAmazonLinux2023,
+	types.AMITypesBottlerocketArm64:       amiFamilyBottlerocket,
+	types.AMITypesBottlerocketX8664:       amiFamilyBottlerocket,
+	types.AMITypesBottlerocketArm64Fips:   amiFamilyBottlerocket,
+	types.AMITypesBottlerocketX8664Fips:   amiFamilyBottlerocket,
+	types.AMITypesBottlerocketArm64Nvidia: amiFamilyBottlerocket,
+	types.AMITypesBottlerocketX8664Nvidia: amiFamilyBottlerocket,
+	types.AMITypesWindowsCore2019X8664:    amiFamilyWindows,
+	types.AMITypesWindowsFull2019X8664:    amiFamilyWindows,
+	types.AMITypesWindowsCore2022X8664:    amiFamilyWindows,
+	types.AMITypesWindowsFull2022X8664:    amiFamilyWindows,
+	types.AMITypesCustom:                  amiFamilyCustom,
+}
+
+// capaAMITypes are the EKS AMI types accepted by the AWSManagedMachinePool
+var capaAMITypes map[types.AMITypes]expinfrav2.ManagedMachineAMIType = map[types.AMITypes]expinfrav2.ManagedMachineAMIType{
+	types.AMITypesAl2X8664:    expinfrav2.Al2x86_64,
+	types.AMITypesAl2X8664Gpu: expinfrav2.Al2x86_64GPU,
+	types.AMITypesAl2Arm64:    expinfrav2.Al2Arm64,
+	types.AMITypesCustom:      expinfrav2.ManagedMachineAMIType(types.AMITypesCustom),
+}
+
+// mapAMIType converts the nodegroup AMI type to the CAPA AMI type and
+// returns the family of AMI it describes
+//
+// AMI types CAPA does not support are left unset, in which case CAPA falls
+// back to AL2_x86_64, and reported as a warning.
+func mapAMIType(amiType types.AMITypes) (mapped *expinfrav2.ManagedMachineAMIType, family amiFamily, warning error) {
+	if amiType == "" {
+		return nil, "", nil
+	}
+
+	var ok bool
+	if family, ok = amiTypeFamilies[amiType]; !ok {
+		return nil, "", fmt.Errorf("unknown AMI type %q", amiType)
+	}
+
+	if capa, ok := capaAMITypes[amiType]; ok {
+		return &capa, family, nil
+	}
+	return nil, family, fmt.Errorf("AMI type %s is not supported by CAPA and will default to %s", amiType, expinfrav2.Al2x86_64)
+}
+
+// imageVersionPattern matches the Kubernetes version in the name of an EKS
+// optimized Amazon Linux image, such as `amazon-eks-node-1.28-v20240202`
+var imageVersionPattern = regexp.MustCompile(`-(\d+\.\d+)-v\d+$`)
+
+// windowsReleasePattern matches the Windows Server release in an EKS AMI
+// type, such as `WINDOWS_CORE_2022_X86_64`, or in the name of an EKS
+// optimized Windows image, such as
+// `Windows_Server-2022-English-Core-EKS_Optimized-1.28-2024.01.09`
+var windowsReleasePattern = regexp.MustCompile(`(?:^WINDOWS_(?:CORE|FULL)_|^Windows_Server-)(\d{4})[-_]`)
+
+// windowsRelease returns the Windows Server release named in an AMI type or
+// image name, or an empty string if there is none
+func windowsRelease(name string) string {
+	if m := windowsReleasePattern.FindStringSubmatch(name); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// resolvedAMI describes the image referenced by a launch template
+type resolvedAMI struct {
+	family amiFamily
+
+	// windowsRelease is the Windows Server release of an EKS optimized
+	// Windows image
+	windowsRelease string
+
+	// kubernetesVersion is the version an EKS optimized image was built for
+	kubernetesVersion string
+}
+
+// imageNamePrefixes maps the name prefixes of the AMIs published by AWS onto
+// their family. Longer prefixes must come first.
+var imageNamePrefixes = []struct {
+	prefix string
+	family amiFamily
+	gpu    bool
+}{
+	{prefix: "amazon-eks-node-al2023-", family: amiFamilyAmazonLinux2023},
+	{prefix: "amazon-eks-gpu-node-", family: amiFamilyAmazonLinux2, gpu: true},
+	{prefix: "amazon-eks-arm64-node-", family: amiFamilyAmazonLinux2},
+	{prefix: "amazon-eks-node-", family: amiFamilyAmazonLinux2},
+	{prefix: "bottlerocket-aws-k8s-", family: amiFamilyBottlerocket},
+}
+
+// classifyImage works out the family of an AMI and whether it is a GPU
+// variant. Only images published by AWS are recognised, anything else is
+// treated as custom.
+func classifyImage(image *ec2types.Image) (family amiFamily, gpu bool) {
+	if aws.ToString(image.ImageOwnerAlias) != "amazon" && aws.ToString(image.OwnerId) != eksAMIOwner {
+		return amiFamilyCustom, false
+	}
+
+	var name string = aws.ToString(image.Name)
+	if image.Platform == ec2types.PlatformValuesWindows && strings.Contains(name, "EKS_Optimized") {
+		return amiFamilyWindows, false
+	}
+
+	for _, p := range imageNamePrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.family, p.gpu
+		}
+	}
+	return amiFamilyCustom, false
+}
+
+// resolveAMI looks up the AMI referenced by the launch template. The image
+// returned is empty if the AMI cannot be described.
+//
+// EKS optimized Amazon Linux 2 images are replaced by an SSM lookup so that
+// CAPA rolls the pool forward with new releases. The lookup is made for the
+// Kubernetes version of the MachinePool, so the image is left pinned if the
+// version it was built for is not known. Other images stay pinned by ID and
+// custom images are flagged with a warning.
+func resolveAMI(template *expinfrav2.AWSLaunchTemplate, client AwsEc2Api) (image resolvedAMI, annotations map[string]string, warnings []error) {
+	annotations = make(map[string]string)
+	if template == nil || template.AMI.ID == nil {
+		return
+	}
+
+	var id string = *template.AMI.ID
+	res, err := DescribeImages(context.TODO(), client, &ec2.DescribeImagesInput{
+		ImageIds: []string{id},
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Errorf("cannot describe AMI %s: %w", id, err))
+		return
+	}
+
+	if res == nil || len(res.Images) == 0 {
+		warnings = append(warnings, fmt.Errorf("AMI %s not found, it may have been deregistered", id))
+		return
+	}
+
+	var gpu bool
+	image.family, gpu = classifyImage(&res.Images[0])
+	annotations[amiFamilyAnnotation] = string(image.family)
+
+	if image.family == amiFamilyWindows {
+		image.windowsRelease = windowsRelease(aws.ToString(res.Images[0].Name))
+	}
+
+	if m := imageVersionPattern.FindStringSubmatch(aws.ToString(res.Images[0].Name)); m != nil {
+		image.kubernetesVersion = m[1]
+	}
+
+	switch image.family {
+	case amiFamilyAmazonLinux2:
+		if image.kubernetesVersion == "" {
+			break
+		}
+
+		var lookup infrav2.EKSAMILookupType = infrav2.AmazonLinux
+		if gpu {
+			lookup = infrav2.AmazonLinuxGPU
+		}
+
+		template.AMI = infrav2.AMIReference{
+			EKSOptimizedLookupType: &lookup,
+		}
+		annotations[amiIDAnnotation] = id
+	case amiFamilyCustom:
+		annotations[amiPinnedAnnotation] = "true"
+		warnings = append(warnings, fmt.Errorf("launch template is pinned to custom AMI %s", id))
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/google/go-cmp/cmp"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+func TestMapAMIType(t *testing.T) {
+	cases := map[types.AMITypes]struct {
+		want       *expinfrav2.ManagedMachineAMIType
+		wantFamily amiFamily
+		wantWarn   bool
+	}{
+		"": {},
+		types.AMITypesAl2X8664: {
+			want: amiTypePtr(expinfrav2.Al2x86_64), wantFamily: amiFamilyAmazonLinux2,
+		},
+		types.AMITypesAl2X8664Gpu: {
+			want: amiTypePtr(expinfrav2.Al2x86_64GPU), wantFamily: amiFamilyAmazonLinux2,
+		},
+		types.AMITypesAl2Arm64: {
+			want: amiTypePtr(expinfrav2.Al2Arm64), wantFamily: amiFamilyAmazonLinux2,
+		},
+		types.AMITypesCustom: {
+			want: amiTypePtr("CUSTOM"), wantFamily: amiFamilyCustom,
+		},
+		types.AMITypesAl2023X8664Standard:     {wantFamily: amiFamilyAmazonLinux2023, wantWarn: true},
+		types.AMITypesAl2023Arm64Standard:     {wantFamily: amiFamilyAmazonLinux2023, wantWarn: true},
+		types.AMITypesAl2023X8664Neuron:       {wantFamily: amiFamilyAmazonLinux2023, wantWarn: true},
+		types.AMITypesAl2023X8664Nvidia:       {wantFamily: amiFamilyAmazonLinux2023, wantWarn: true},
+		types.AMITypesAl2023Arm64Nvidia:       {wantFamily: amiFamilyAmazonLinux2023, wantWarn: true},
+		types.AMITypesBottlerocketArm64:       {wantFamily: amiFamilyBottlerocket, wantWarn: true},
+		types.AMITypesBottlerocketX8664:       {wantFamily: amiFamilyBottlerocket, wantWarn: true},
+		types.AMITypesBottlerocketArm64Fips:   {wantFamily: amiFamilyBottlerocket, wantWarn: true},
+		types.AMITypesBottlerocketX8664Fips:   {wantFamily: amiFamilyBottlerocket, wantWarn: true},
+		types.AMITypesBottlerocketArm64Nvidia: {wantFamily: amiFamilyBottlerocket, wantWarn: true},
+		types.AMITypesBottlerocketX8664Nvidia: {wantFamily: amiFamilyBottlerocket, wantWarn: true},
+		types.AMITypesWindowsCore2019X8664:    {wantFamily: amiFamilyWindows, wantWarn: true},
+		types.AMITypesWindowsFull2019X8664:    {wantFamily: amiFamilyWindows, wantWarn: true},
+		types.AMITypesWindowsCore2022X8664:    {wantFamily: amiFamilyWindows, wantWarn: true},
+		types.AMITypesWindowsFull2022X8664:    {wantFamily: amiFamilyWindows, wantWarn: true},
+		"SOMETHING_NEW":                       {wantWarn: true},
+	}
+
+	for amiType, tc := range cases {
+		t.Run(string(amiType), func(t *testing.T) {
+			got, family, warning := mapAMIType(amiType)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mapAMIType(%q): -want, +got:\n%s", amiType, diff)
+			}
+
+			if family != tc.wantFamily {
+				t.Errorf("mapAMIType(%q): want family %s, got %s", amiType, tc.wantFamily, family)
+			}
+
+			if (warning != nil) != tc.wantWarn {
+				t.Errorf("mapAMIType(%q): unexpected warning state: %v", amiType, warning)
+			}
+		})
+	}
+}
+
+func amiTypePtr(t expinfrav2.ManagedMachineAMIType) *expinfrav2.ManagedMachineAMIType {
+	return &t
+}
+
+func TestClassifyImage(t *testing.T) {
+	cases := map[string]struct {
+		image      ec2types.Image
+		wantFamily amiFamily
+		wantGpu    bool
+	}{
+		"AL2 x86_64": {
+			image: ec2types.Image{
+				ImageOwnerAlias: aws.String("amazon"),
+				Name:            aws.String("amazon-eks-node-1.28-v20240202"),
+			},
+			wantFamily: amiFamilyAmazonLinux2,
+		},
+		"AL2 arm64": {
+			image: ec2types.Image{
+				OwnerId: aws.String(eksAMIOwner),
+				Name:    aws.String("amazon-eks-arm64-node-1.28-v20240202"),
+			},
+			wantFamily: amiFamilyAmazonLinux2,
+		},
+		"AL2 GPU": {
+			image: ec2types.Image{
+				ImageOwnerAlias: aws.String("amazon"),
+				Name:            aws.String("amazon-eks-gpu-node-1.28-v20240202"),
+			},
+			wantFamily: amiFamilyAmazonLinux2,
+			wantGpu:    true,
+		},
+		"AL2023": {
+			image: ec2types.Image{
+				ImageOwnerAlias: aws.String("amazon"),
+				Name:            aws.String("amazon-eks-node-al2023-x86_64-standard-1.29-v20240202"),
+			},
+			wantFamily: amiFamilyAmazonLinux2023,
+		},
+		"Bottlerocket": {
+			image: ec2types.Image{
+				ImageOwnerAlias: aws.String("amazon"),
+				Name:            aws.String("bottlerocket-aws-k8s-1.28-x86_64-v1.19.0-0d9d9e5f"),
+			},
+			wantFamily: amiFamilyBottlerocket,
+		},
+		"Windows": {
+			image: ec2types.Image{
+				ImageOwnerAlias: aws.String("amazon"),
+				Name:            aws.String("Windows_Server-2022-English-Core-EKS_Optimized-1.28-2024.01.09"),
+				Platform:        ec2types.PlatformValuesWindows,
+			},
+			wantFamily: amiFamilyWindows,
+		},
+		"custom image copying an EKS name": {
+			image: ec2types.Image{
+				OwnerId: aws.String("123456789012"),
+				Name:    aws.String("amazon-eks-node-1.28-v20240202"),
+			},
+			wantFamily: amiFamilyCustom,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			family, gpu := classifyImage(&tc.image)
+			if family != tc.wantFamily || gpu != tc.wantGpu {
+				t.Errorf("classifyImage(...): want %s (gpu %t), got %s (gpu %t)", tc.wantFamily, tc.wantGpu, family, gpu)
+			}
+		})
+	}
+}
+
+func TestWindowsRelease(t *testing.T) {
+	cases := map[string]string{
+		"WINDOWS_CORE_2019_X86_64":                                       "2019",
+		"WINDOWS_FULL_2022_X86_64":                                       "2022",
+		"Windows_Server-2022-English-Core-EKS_Optimized-1.28-2024.01.09": "2022",
+		"Windows_Server-2019-English-Full-EKS_Optimized-1.24-2022.12.14": "2019",
+		"AL2_x86_64":                     "",
+		"amazon-eks-node-1.28-v20220202": "",
+	}
+
+	for name, want := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := windowsRelease(name); got != want {
+				t.Errorf("windowsRelease(%q): want %q, got %q", name, want, got)
+			}
+		})
+	}
+}
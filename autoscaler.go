@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capiinfra "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// autoscalerMinSizeAnnotation is read by the cluster-autoscaler CAPI
+	// provider for the lower bound of a MachinePool
+	autoscalerMinSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size"
+
+	// autoscalerMaxSizeAnnotation is read by the cluster-autoscaler CAPI
+	// provider for the upper bound of a MachinePool
+	autoscalerMaxSizeAnnotation = "cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size"
+
+	// autoscalerNodesKey is the ConfigMap key holding the node groups in the
+	// form taken by the `--nodes` flag of the AWS cloud provider
+	autoscalerNodesKey = "nodes"
+)
+
+// autoscalerNodeGroup holds the bounds of an autoscaling group
+type autoscalerNodeGroup struct {
+	name     string
+	min, max int32
+}
+
+// setAutoscalerBounds annotates the MachinePool with the size bounds of the
+// pool and records the autoscaling group behind it for the ConfigMap
+func setAutoscalerBounds(ac *XrConfig, machinepool *capiinfra.MachineDeployment, asgName string, min, max int32) {
+	if machinepool.Annotations == nil {
+		machinepool.Annotations = make(map[string]string)
+	}
+	machinepool.Annotations[autoscalerMinSizeAnnotation] = strconv.Itoa(int(min))
+	machinepool.Annotations[autoscalerMaxSizeAnnotation] = strconv.Itoa(int(max))
+
+	if asgName != "" {
+		ac.autoscalerNodeGroups = append(ac.autoscalerNodeGroups, autoscalerNodeGroup{
+			name: asgName,
+			min:  min,
+			max:  max,
+		})
+	}
+}
+
+// addAutoscalerConfig adds a ConfigMap listing the bounds of each autoscaling
+// group for clusters still running the AWS cloud provider of the
+// cluster-autoscaler. Failures to add the ConfigMap are logged by addDesired.
+func (f *Function) addAutoscalerConfig(ac *XrConfig) {
+	if len(ac.autoscalerNodeGroups) == 0 {
+		return
+	}
+
+	var (
+		nodes  []string
+		source provenance
+	)
+	for _, group := range ac.autoscalerNodeGroups {
+		nodes = append(nodes, fmt.Sprintf("%d:%d:%s", group.min, group.max, group.name))
+		source.autoscalingGroups = append(source.autoscalingGroups, group.name)
+	}
+
+	var name string = objectName(ac, "cluster-autoscaler", "")
+	var configmap v1.ConfigMap = v1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   *ac.namespace,
+			Labels:      newLabels(ac, "ConfigMap", ""),
+			Annotations: newAnnotations(ac, "ConfigMap"),
+		},
+		Data: map[string]string{
+			autoscalerNodesKey: strings.Join(nodes, "\n") + "\n",
+		},
+	}
+	source.hash = sourceHash(nodes)
+	_ = f.addDesired(ac, name, configmap, source)
+}
@@ -7,6 +7,7 @@ import (
 	asg "github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	xfnaws "github.com/giantswarm/xfnlib/pkg/auth/aws"
 )
 
@@ -15,6 +16,14 @@ type AwsEc2Api interface {
 	DescribeLaunchTemplateVersions(ctx context.Context,
 		params *ec2.DescribeLaunchTemplateVersionsInput,
 		optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error)
+
+	DescribeImages(ctx context.Context,
+		params *ec2.DescribeImagesInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error)
+
+	DescribeInstances(ctx context.Context,
+		params *ec2.DescribeInstancesInput,
+		optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
 }
 
 // DescribeLaunchTemplateVersions Get the EC2 Launch template versions for a given launch template
@@ -22,6 +31,16 @@ func DescribeLaunchTemplateVersions(c context.Context, api AwsEc2Api, input *ec2
 	return api.DescribeLaunchTemplateVersions(c, input)
 }
 
+// DescribeImages Get the details of one or more AMIs
+func DescribeImages(c context.Context, api AwsEc2Api, input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	return api.DescribeImages(c, input)
+}
+
+// DescribeInstances Get the details of the EC2 instances matching the filters
+func DescribeInstances(c context.Context, api AwsEc2Api, input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	return api.DescribeInstances(c, input)
+}
+
 // EKSNodegroupAPI describes the AWS functions required by this composition function
 // in order to track nodegroup objects for the desired cluster
 type AwsEksApi interface {
@@ -32,6 +51,42 @@ type AwsEksApi interface {
 	DescribeNodegroup(ctx context.Context,
 		params *eks.DescribeNodegroupInput,
 		optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error)
+
+	ListFargateProfiles(ctx context.Context,
+		params *eks.ListFargateProfilesInput,
+		optFns ...func(*eks.Options)) (*eks.ListFargateProfilesOutput, error)
+
+	DescribeFargateProfile(ctx context.Context,
+		params *eks.DescribeFargateProfileInput,
+		optFns ...func(*eks.Options)) (*eks.DescribeFargateProfileOutput, error)
+
+	DescribeCluster(ctx context.Context,
+		params *eks.DescribeClusterInput,
+		optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error)
+
+	ListIdentityProviderConfigs(ctx context.Context,
+		params *eks.ListIdentityProviderConfigsInput,
+		optFns ...func(*eks.Options)) (*eks.ListIdentityProviderConfigsOutput, error)
+
+	DescribeIdentityProviderConfig(ctx context.Context,
+		params *eks.DescribeIdentityProviderConfigInput,
+		optFns ...func(*eks.Options)) (*eks.DescribeIdentityProviderConfigOutput, error)
+
+	ListAddons(ctx context.Context,
+		params *eks.ListAddonsInput,
+		optFns ...func(*eks.Options)) (*eks.ListAddonsOutput, error)
+
+	DescribeAddon(ctx context.Context,
+		params *eks.DescribeAddonInput,
+		optFns ...func(*eks.Options)) (*eks.DescribeAddonOutput, error)
+
+	ListUpdates(ctx context.Context,
+		params *eks.ListUpdatesInput,
+		optFns ...func(*eks.Options)) (*eks.ListUpdatesOutput, error)
+
+	DescribeUpdate(ctx context.Context,
+		params *eks.DescribeUpdateInput,
+		optFns ...func(*eks.Options)) (*eks.DescribeUpdateOutput, error)
 }
 
 // GetNodegroups Get the nodegroups attached to the provided cluster
@@ -45,6 +100,51 @@ func DescribeNodegroup(c context.Context, api AwsEksApi, input *eks.DescribeNode
 	return api.DescribeNodegroup(c, input)
 }
 
+// ListFargateProfiles Get the fargate profiles attached to the provided cluster
+func ListFargateProfiles(c context.Context, api AwsEksApi, input *eks.ListFargateProfilesInput) (*eks.ListFargateProfilesOutput, error) {
+	return api.ListFargateProfiles(c, input)
+}
+
+// DescribeFargateProfile Describe a single fargate profile
+func DescribeFargateProfile(c context.Context, api AwsEksApi, input *eks.DescribeFargateProfileInput) (*eks.DescribeFargateProfileOutput, error) {
+	return api.DescribeFargateProfile(c, input)
+}
+
+// DescribeCluster Describe the provided cluster
+func DescribeCluster(c context.Context, api AwsEksApi, input *eks.DescribeClusterInput) (*eks.DescribeClusterOutput, error) {
+	return api.DescribeCluster(c, input)
+}
+
+// ListIdentityProviderConfigs Get the identity provider configs associated with the provided cluster
+func ListIdentityProviderConfigs(c context.Context, api AwsEksApi, input *eks.ListIdentityProviderConfigsInput) (*eks.ListIdentityProviderConfigsOutput, error) {
+	return api.ListIdentityProviderConfigs(c, input)
+}
+
+// DescribeIdentityProviderConfig Describe a single identity provider config
+func DescribeIdentityProviderConfig(c context.Context, api AwsEksApi, input *eks.DescribeIdentityProviderConfigInput) (*eks.DescribeIdentityProviderConfigOutput, error) {
+	return api.DescribeIdentityProviderConfig(c, input)
+}
+
+// ListAddons Get the add-ons installed on the provided cluster
+func ListAddons(c context.Context, api AwsEksApi, input *eks.ListAddonsInput) (*eks.ListAddonsOutput, error) {
+	return api.ListAddons(c, input)
+}
+
+// DescribeAddon Describe a single add-on
+func DescribeAddon(c context.Context, api AwsEksApi, input *eks.DescribeAddonInput) (*eks.DescribeAddonOutput, error) {
+	return api.DescribeAddon(c, input)
+}
+
+// ListUpdates Get the updates made to a cluster, nodegroup or add-on
+func ListUpdates(c context.Context, api AwsEksApi, input *eks.ListUpdatesInput) (*eks.ListUpdatesOutput, error) {
+	return api.ListUpdates(c, input)
+}
+
+// DescribeUpdate Describe a single update
+func DescribeUpdate(c context.Context, api AwsEksApi, input *eks.DescribeUpdateInput) (*eks.DescribeUpdateOutput, error) {
+	return api.DescribeUpdate(c, input)
+}
+
 // AutoscalingAPI presents functions required for reading autoscaling groups from AWS
 type AwsAsgApi interface {
 	DescribeAutoScalingGroups(ctx context.Context,
@@ -57,6 +157,27 @@ func GetAutoScalingGroups(c context.Context, api AwsAsgApi, input *asg.DescribeA
 	return api.DescribeAutoScalingGroups(c, input)
 }
 
+// AwsIamApi presents the functions required for reading IAM role details
+type AwsIamApi interface {
+	ListAttachedRolePolicies(ctx context.Context,
+		params *iam.ListAttachedRolePoliciesInput,
+		optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error)
+
+	ListOpenIDConnectProviders(ctx context.Context,
+		params *iam.ListOpenIDConnectProvidersInput,
+		optFns ...func(*iam.Options)) (*iam.ListOpenIDConnectProvidersOutput, error)
+}
+
+// ListAttachedRolePolicies Get the managed policies attached to an IAM role
+func ListAttachedRolePolicies(c context.Context, api AwsIamApi, input *iam.ListAttachedRolePoliciesInput) (*iam.ListAttachedRolePoliciesOutput, error) {
+	return api.ListAttachedRolePolicies(c, input)
+}
+
+// ListOpenIDConnectProviders Get the OIDC providers registered in the account
+func ListOpenIDConnectProviders(c context.Context, api AwsIamApi, input *iam.ListOpenIDConnectProvidersInput) (*iam.ListOpenIDConnectProvidersOutput, error) {
+	return api.ListOpenIDConnectProviders(c, input)
+}
+
 var (
 	getEc2Client = func(cfg aws.Config) AwsEc2Api {
 		return ec2.NewFromConfig(cfg)
@@ -70,6 +191,10 @@ var (
 		return asg.NewFromConfig(cfg)
 	}
 
+	getIamClient = func(cfg aws.Config) AwsIamApi {
+		return iam.NewFromConfig(cfg)
+	}
+
 	awsConfig = func(region, provider *string) (aws.Config, error) {
 		return xfnaws.Config(region, provider)
 	}
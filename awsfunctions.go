@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/eks/types"
 	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	eksbootstrapv1 "sigs.k8s.io/cluster-api-provider-aws/v2/bootstrap/eks/api/v1beta2"
 	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -21,6 +23,20 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	capiinfra "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+// launchTemplateAnnotationPrefix is the prefix given to annotations recording
+// launch template settings that have no equivalent field in CAPA
+const launchTemplateAnnotationPrefix = "launchtemplate.describenodegroups.fn.giantswarm.io/"
+
+const (
+	// rolePathAnnotation records the IAM path of the node role when it is not `/`
+	rolePathAnnotation = "describenodegroups.fn.giantswarm.io/role-path"
+
+	// updateStrategyAnnotation records the nodegroup update strategy
+	updateStrategyAnnotation = "describenodegroups.fn.giantswarm.io/update-strategy"
 )
 
 // CreateAWSNodegroupSpec will attempt to determine how the nodegroup is defined
@@ -29,10 +45,24 @@ import (
 // This function will output both a MachinePool and an AWSManagedMachinepool object
 func (f *Function) CreateAWSNodegroupSpec(ac *XrConfig) (err error) {
 	var (
-		res *eks.ListNodegroupsOutput
-		cfg aws.Config
+		res       *eks.ListNodegroupsOutput
+		cfg       aws.Config
+		filter    *tagFilter
+		selection *nodegroupFilter
 	)
 
+	if filter, err = newTagFilter(ac.input.Tags); err != nil {
+		return
+	}
+
+	if selection, err = newNodegroupFilter(ac.input.Nodegroups); err != nil {
+		return
+	}
+
+	var warnings []error
+	ac.overrides, warnings = newNodegroupOverrides(ac.input.NodegroupOverrides)
+	ac.warnings = append(ac.warnings, warnings...)
+
 	if cfg, err = awsConfig(ac.region, ac.providerConfigRef); err != nil {
 		err = errors.Wrap(err, "failed to load aws config for assume role")
 		return
@@ -41,6 +71,16 @@ func (f *Function) CreateAWSNodegroupSpec(ac *XrConfig) (err error) {
 	eksclient := getEksClient(cfg)
 	ec2client := getEc2Client(cfg)
 	asgclient := getAsgClient(cfg)
+	controlPlane := controlPlaneVersion(ac.observedCluster)
+
+	var iamclient AwsIamApi
+	if ac.input.ImportRolePolicies {
+		iamclient = getIamClient(cfg)
+	}
+
+	if ac.input.ImportControlPlane {
+		f.importControlPlane(ac, eksclient, getIamClient(cfg), filter)
+	}
 
 	clusterInput := &eks.ListNodegroupsInput{
 		ClusterName: ac.cluster,
@@ -60,18 +100,49 @@ func (f *Function) CreateAWSNodegroupSpec(ac *XrConfig) (err error) {
 		var group *eks.DescribeNodegroupOutput
 		if group, err = DescribeNodegroup(context.TODO(), eksclient, nodegroupInput); err != nil {
 			f.log.Debug("AWSAPI", "cannot describe nodegroup", nodegroup, "cluster", *ac.cluster, "error", err)
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot describe nodegroup %q", nodegroup))
+			continue
+		}
+
+		if !selection.allowed(nodegroupTarget(group.Nodegroup)) {
+			f.log.Debug("AWSAPI", "skipping nodegroup excluded by the input", nodegroup, "cluster", *ac.cluster)
 			continue
 		}
 
-		var ng *expinfrav2.AWSManagedMachinePoolSpec
-		if ng, err = f.nodegroupToCapiObject(group.Nodegroup, ec2client, asgclient); err != nil {
+		var ng *NodegroupConfig
+		if ng, err = f.nodegroupToCapiObject(group.Nodegroup, ec2client, asgclient, iamclient, filter); err != nil {
 			f.log.Debug("AWSAPI", "cannot create nodegroup", nodegroup, "cluster", *ac.cluster, "error", err)
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "nodegroup %q cannot be imported", nodegroup))
 			continue
 		}
 
-		ac.labels["giantswarm.io/machine-pool"] = nodegroup
-		var nodegroupName string = fmt.Sprintf("%s-awsmanagedmachinepool-%s", *ac.cluster, nodegroup)
+		if controlPlane != "" && group.Nodegroup.Version != nil {
+			if err = checkVersionSkew(controlPlane, *group.Nodegroup.Version); err != nil {
+				ng.warnings = append(ng.warnings, err)
+			}
+		}
+
+		var override *v1beta1.NodegroupOverride = ac.overrides.get(nodegroup)
+		if warning := applyLaunchTemplateOverride(override, ng.spec.AWSLaunchTemplate, ng.annotations); warning != nil {
+			ng.warnings = append(ng.warnings, warning)
+		}
+		pinManagedAMI(override, ng.spec)
+
+		for _, warning := range ng.warnings {
+			ac.warnings = append(ac.warnings, errors.Wrapf(warning, "nodegroup %q", nodegroup))
+		}
+
+		var (
+			nodegroupName string     = objectName(ac, "awsmanagedmachinepool", nodegroup)
+			source        provenance = nodegroupProvenance(group.Nodegroup, ng)
+		)
 		f.log.Info("AWSAPI", "Creating nodegroup", nodegroupName)
+
+		var annotations map[string]string = newAnnotations(ac, "AWSManagedMachinePool")
+		for k, v := range ng.annotations {
+			annotations[k] = v
+		}
+
 		var awsmmp expinfrav2.AWSManagedMachinePool = expinfrav2.AWSManagedMachinePool{
 			TypeMeta: metav1.TypeMeta{
 				Kind:       "AWSManagedMachinePool",
@@ -80,89 +151,232 @@ func (f *Function) CreateAWSNodegroupSpec(ac *XrConfig) (err error) {
 			ObjectMeta: metav1.ObjectMeta{
 				Name:        nodegroupName,
 				Namespace:   *ac.namespace,
-				Labels:      ac.labels,
-				Annotations: ac.annotations,
+				Labels:      newLabels(ac, "AWSManagedMachinePool", nodegroup),
+				Annotations: annotations,
 			},
-			Spec: *ng,
+			Spec: *ng.spec,
 			Status: expinfrav2.AWSManagedMachinePoolStatus{
-				Ready:                 true,
-				Replicas:              int32(len(ng.ProviderIDList)),
-				LaunchTemplateID:      group.Nodegroup.LaunchTemplate.Id,
-				LaunchTemplateVersion: group.Nodegroup.LaunchTemplate.Version,
+				Ready:    true,
+				Replicas: int32(len(ng.spec.ProviderIDList)),
 			},
 		}
 
-		var dataSecretName string = ""
-		var machinepoolName string = fmt.Sprintf("%s-machinepool-%s", *ac.cluster, nodegroup)
-		f.log.Info("AWSAPI", "Creating machinepool", machinepoolName)
-		var machinepool *capiinfra.MachineDeployment = &capiinfra.MachineDeployment{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "MachinePool",
-				APIVersion: "cluster.x-k8s.io/v1beta1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:        machinepoolName,
-				Namespace:   *ac.namespace,
-				Labels:      ac.labels,
-				Annotations: ac.annotations,
-			},
-			Spec: capiinfra.MachineDeploymentSpec{
-				Replicas:    &awsmmp.Status.Replicas,
-				ClusterName: *ac.cluster,
-				Template: capiinfra.MachineTemplateSpec{
-					Spec: capiinfra.MachineSpec{
-						ClusterName: *ac.cluster,
-						Bootstrap: capiinfra.Bootstrap{
-							DataSecretName: &dataSecretName,
-						},
-						InfrastructureRef: v1.ObjectReference{
-							Kind:       "AWSManagedMachinePool",
-							APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
-							Namespace:  *ac.namespace,
-							Name:       nodegroupName,
-						},
-					},
-				},
-			},
+		// Nodegroups created without a launch template have none to report
+		if group.Nodegroup.LaunchTemplate != nil {
+			var (
+				id      string = aws.ToString(group.Nodegroup.LaunchTemplate.Id)
+				version string = aws.ToString(group.Nodegroup.LaunchTemplate.Version)
+			)
+			awsmmp.Status.LaunchTemplateID = &id
+			awsmmp.Status.LaunchTemplateVersion = &version
 		}
 
-		var awsobject, mpobject *unstructured.Unstructured
-		if awsobject, err = composite.ToUnstructuredKubernetesObject(awsmmp, ac.composite.Spec.ClusterProviderConfigRef, ac.composite.Spec.ObjectDeletionPolicy); err != nil {
-			f.log.Debug("failed to convert nodegroup", nodegroupName, "cluster", *ac.cluster, "error", err, "object", awsmmp)
-			continue
+		var eksconfig, bootstrap = newEKSConfig(ac, nodegroup, ng.bootstrap)
+		var machinepool *capiinfra.MachineDeployment = newMachinePool(ac, nodegroup, &awsmmp.Status.Replicas,
+			kubernetesVersion(group.Nodegroup), bootstrap, v1.ObjectReference{
+				Kind:       "AWSManagedMachinePool",
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+				Namespace:  *ac.namespace,
+				Name:       nodegroupName,
+			})
+
+		if ac.input.ClusterAutoscaler && ng.spec.Scaling != nil {
+			var asgName string
+			if len(source.autoscalingGroups) > 0 {
+				asgName = source.autoscalingGroups[0]
+			}
+			setAutoscalerBounds(ac, machinepool, asgName, aws.ToInt32(ng.spec.Scaling.MinSize), aws.ToInt32(ng.spec.Scaling.MaxSize))
 		}
 
-		f.log.Info("Adding nodegroup to required resources", "nodegroup", nodegroupName)
-		if err = ac.composed.AddDesired(nodegroupName, awsobject); err != nil {
-			f.log.Debug("failed to add nodegroup", nodegroupName, "cluster", *ac.cluster, "error", err, "object", awsobject)
-			continue
+		setDeletionPolicy(ac, override, nodegroupName, machinepool.Name)
+		if eksconfig != nil {
+			setDeletionPolicy(ac, override, eksconfig.Name)
+			if err = f.addDesired(ac, eksconfig.Name, eksconfig, source); err != nil {
+				continue
+			}
 		}
 
-		if mpobject, err = composite.ToUnstructuredKubernetesObject(machinepool, ac.composite.Spec.ClusterProviderConfigRef, ac.composite.Spec.ObjectDeletionPolicy); err != nil {
-			f.log.Debug("failed to convert machinepool", machinepoolName, "cluster", *ac.cluster, "error", err, "object", machinepool)
+		if err = f.addDesired(ac, nodegroupName, awsmmp, source); err != nil {
 			continue
 		}
 
-		f.log.Info("Adding machinepool to required resources", "machinepool", nodegroupName)
-		if err = ac.composed.AddDesired(machinepoolName, mpobject); err != nil {
-			f.log.Debug("failed to add machinepool", machinepoolName, "cluster", *ac.cluster, "error", err, "object", mpobject)
+		if err = f.addDesired(ac, machinepool.Name, machinepool, source); err != nil {
 			continue
 		}
+
+		if ac.input.GenerateKarpenter {
+			nodePool, nodeClass, warnings := nodegroupToKarpenter(*ac.cluster, objectName(ac, "nodepool", nodegroup),
+				objectName(ac, "ec2nodeclass", nodegroup), group.Nodegroup, ng)
+			for _, warning := range warnings {
+				ac.warnings = append(ac.warnings, errors.Wrapf(warning, "nodegroup %q", nodegroup))
+			}
+
+			nodeClass.Labels, nodeClass.Annotations = newLabels(ac, "EC2NodeClass", nodegroup), newAnnotations(ac, "EC2NodeClass")
+			nodePool.Labels, nodePool.Annotations = newLabels(ac, "NodePool", nodegroup), newAnnotations(ac, "NodePool")
+			setDeletionPolicy(ac, override, nodeClass.Name, nodePool.Name)
+			if err = f.addDesired(ac, nodeClass.Name, nodeClass, source); err != nil {
+				continue
+			}
+
+			if err = f.addDesired(ac, nodePool.Name, nodePool, source); err != nil {
+				continue
+			}
+		}
+	}
+
+	if ac.input.ImportFargateProfiles {
+		f.importFargateProfiles(ac, eksclient, filter, selection)
+	}
+
+	if ac.input.SelfManaged {
+		if err = f.importAutoscalingGroups(ac, ec2client, asgclient, filter, selection); err != nil {
+			return
+		}
+	}
+
+	if ac.input.DiscoverKarpenter {
+		f.discoverKarpenter(ac, ec2client)
 	}
+
+	if ac.input.ClusterAutoscaler {
+		f.addAutoscalerConfig(ac)
+	}
+
+	ac.warnings = append(ac.warnings, ac.overrides.unmatched()...)
 	return nil
 }
 
+// newEKSConfig builds the EKSConfig for a pool and the bootstrap reference
+// given to its MachinePool. Without a bootstrap spec no EKSConfig is built
+// and the MachinePool is given an empty data secret name.
+func newEKSConfig(ac *XrConfig, pool string, spec *eksbootstrapv1.EKSConfigSpec) (eksconfig *eksbootstrapv1.EKSConfig, bootstrap capiinfra.Bootstrap) {
+	if spec == nil {
+		var dataSecretName string = ""
+		bootstrap.DataSecretName = &dataSecretName
+		return
+	}
+
+	var name string = objectName(ac, "eksconfig", pool)
+	eksconfig = &eksbootstrapv1.EKSConfig{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "EKSConfig",
+			APIVersion: "bootstrap.cluster.x-k8s.io/v1beta2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   *ac.namespace,
+			Labels:      newLabels(ac, "EKSConfig", pool),
+			Annotations: newAnnotations(ac, "EKSConfig"),
+		},
+		Spec: *spec,
+	}
+
+	bootstrap.ConfigRef = &v1.ObjectReference{
+		Kind:       "EKSConfig",
+		APIVersion: "bootstrap.cluster.x-k8s.io/v1beta2",
+		Namespace:  *ac.namespace,
+		Name:       name,
+	}
+	return
+}
+
+// newMachinePool builds the CAPI MachinePool for a pool referencing the given
+// infrastructure object
+func newMachinePool(ac *XrConfig, pool string, replicas *int32, version *string, bootstrap capiinfra.Bootstrap, infrastructureRef v1.ObjectReference) *capiinfra.MachineDeployment {
+	return &capiinfra.MachineDeployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "MachinePool",
+			APIVersion: "cluster.x-k8s.io/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        objectName(ac, "machinepool", pool),
+			Namespace:   *ac.namespace,
+			Labels:      newLabels(ac, "MachinePool", pool),
+			Annotations: newAnnotations(ac, "MachinePool"),
+		},
+		Spec: capiinfra.MachineDeploymentSpec{
+			Replicas:    replicas,
+			ClusterName: clusterName(ac),
+			Template: capiinfra.MachineTemplateSpec{
+				Spec: capiinfra.MachineSpec{
+					ClusterName:       clusterName(ac),
+					Version:           version,
+					Bootstrap:         bootstrap,
+					InfrastructureRef: infrastructureRef,
+				},
+			},
+		},
+	}
+}
+
+// addDesired annotates an object with the AWS resources it was imported from,
+// wraps it for provider-kubernetes and adds it to the desired resources under
+// the given name
+//
+// Objects with an invalid name, or a name already given to another object,
+// are skipped and reported as warnings.
+func (f *Function) addDesired(ac *XrConfig, name string, object any, source provenance) (err error) {
+	var manifest map[string]any
+	if err = composite.To(object, &manifest); err != nil {
+		f.log.Debug("failed to convert object", name, "cluster", *ac.cluster, "error", err, "object", object)
+		return
+	}
+
+	if err = setProvenance(ac, name, manifest, source); err != nil {
+		f.log.Debug("failed to set provenance", name, "cluster", *ac.cluster, "error", err, "object", object)
+		return
+	}
+
+	manifest = applyPatches(ac, object, manifest)
+
+	var (
+		metaName, _, _ = unstructured.NestedString(manifest, "metadata", "name")
+		kind, _, _     = unstructured.NestedString(manifest, "kind")
+	)
+	if err = claimName(ac, metaName, kind); err != nil {
+		ac.warnings = append(ac.warnings, err)
+		return
+	}
+
+	var deletionPolicy string = ac.deletionPolicy
+	if policy, ok := ac.deletionPolicies[name]; ok {
+		deletionPolicy = policy
+	}
+
+	var u *unstructured.Unstructured
+	if u, err = composite.ToUnstructuredKubernetesObject(manifest, ac.clusterProviderConfigRef, deletionPolicy); err != nil {
+		f.log.Debug("failed to convert object", name, "cluster", *ac.cluster, "error", err, "object", object)
+		return
+	}
+
+	f.log.Info("Adding object to required resources", "name", name)
+	if err = ac.composed.AddDesired(name, u); err != nil {
+		f.log.Debug("failed to add object", name, "cluster", *ac.cluster, "error", err, "object", u)
+	}
+	return
+}
+
 // Pull all the information together to create a AWSManagedMachinePool object
-func (f *Function) nodegroupToCapiObject(group *types.Nodegroup, ec2client AwsEc2Api, asgclient AwsAsgApi) (pool *expinfrav2.AWSManagedMachinePoolSpec, err error) {
-	pool = &expinfrav2.AWSManagedMachinePoolSpec{}
+//
+// If iamclient is nil, the policies attached to the node role are not looked up
+func (f *Function) nodegroupToCapiObject(group *types.Nodegroup, ec2client AwsEc2Api, asgclient AwsAsgApi, iamclient AwsIamApi, filter *tagFilter) (ng *NodegroupConfig, err error) {
 	var (
+		pool              *expinfrav2.AWSManagedMachinePoolSpec = &expinfrav2.AWSManagedMachinePoolSpec{}
 		asgName           string
 		asg               *asgtypes.AutoScalingGroup
 		asgLaunchTemplate *expinfrav2.AWSLaunchTemplate
+		launchTemplate    *LaunchTemplateConfig
+		family            amiFamily
+		warning           error
 	)
+	ng = &NodegroupConfig{
+		spec:        pool,
+		annotations: make(map[string]string),
+	}
 
-	if group.Resources != nil {
-		asgName = *group.Resources.AutoScalingGroups[0].Name
+	// Nodegroups still being created or which failed have no autoscaling group
+	if group.Resources != nil && len(group.Resources.AutoScalingGroups) > 0 {
+		asgName = aws.ToString(group.Resources.AutoScalingGroups[0].Name)
 	}
 
 	if asg, asgLaunchTemplate, err = getAutoscaling(asgName, asgclient, ec2client); err != nil {
@@ -170,12 +384,37 @@ func (f *Function) nodegroupToCapiObject(group *types.Nodegroup, ec2client AwsEc
 			return nil, err
 		}
 	}
-	pool.AMIType = (*expinfrav2.ManagedMachineAMIType)(&group.AmiType)
+	if pool.AMIType, family, warning = mapAMIType(group.AmiType); warning != nil {
+		ng.warnings = append(ng.warnings, warning)
+	}
+
+	if pool.AMIType == nil && group.AmiType != "" {
+		ng.annotations[amiTypeAnnotation] = string(group.AmiType)
+	}
 	pool.AvailabilityZones = asg.AvailabilityZones
 
-	if pool.AWSLaunchTemplate, err = getLaunchTemplate(group.LaunchTemplate, ec2client); err != nil {
+	if launchTemplate, err = getLaunchTemplate(group.LaunchTemplate, ec2client); err != nil {
 		f.log.Debug("AWSAPI", "AWSLaunchTemplate error", err)
 	}
+	ng.sources = []any{nodegroupSource(group), asgSource(asg), launchTemplateSource(launchTemplate)}
+
+	// Tags are layered from the least to the most specific source so that
+	// instance tags on the launch template win over any others
+	var tags map[string]string = make(map[string]string)
+	filter.merge(tags, asgTags(asg.Tags))
+	filter.merge(tags, group.Tags)
+
+	if launchTemplate != nil {
+		pool.AWSLaunchTemplate = launchTemplate.template
+		for k, v := range launchTemplate.annotations {
+			ng.annotations[k] = v
+		}
+		filter.merge(tags, launchTemplate.tags)
+	}
+
+	if len(tags) > 0 {
+		pool.AdditionalTags = infrav2.Tags(tags)
+	}
 
 	if pool.AWSLaunchTemplate == nil {
 		pool.InstanceType = &group.InstanceTypes[0]
@@ -195,18 +434,47 @@ func (f *Function) nodegroupToCapiObject(group *types.Nodegroup, ec2client AwsEc
 				pool.AWSLaunchTemplate.IamInstanceProfile = asgLaunchTemplate.IamInstanceProfile
 			}
 		}
+
+		resolved, annotations, warnings := resolveAMI(pool.AWSLaunchTemplate, ec2client)
+		for k, v := range annotations {
+			ng.annotations[k] = v
+		}
+		ng.warnings = append(ng.warnings, warnings...)
+
+		// A custom AMI may still be one of the families published by AWS
+		if family == amiFamilyCustom && resolved.family != "" {
+			family = resolved.family
+			ng.windowsRelease = resolved.windowsRelease
+		}
+	}
+
+	ng.family = family
+	if ng.windowsRelease == "" {
+		ng.windowsRelease = windowsRelease(string(group.AmiType))
+	}
+
+	if launchTemplate != nil && launchTemplate.userData != nil {
+		var err error
+		var warnings []error
+		if ng.bootstrap, warnings, err = parseUserData(*launchTemplate.userData, family, ng.annotations); err != nil {
+			ng.warnings = append(ng.warnings, errors.Wrap(err, "cannot import bootstrap configuration"))
+		}
+		ng.warnings = append(ng.warnings, warnings...)
 	}
 
 	var capacityTypes map[types.CapacityTypes]expinfrav2.ManagedMachinePoolCapacityType = map[types.CapacityTypes]expinfrav2.ManagedMachinePoolCapacityType{
 		types.CapacityTypesOnDemand: expinfrav2.ManagedMachinePoolCapacityTypeOnDemand,
-		types.CapacityTypesSpot:     expinfrav2.ManagedMachinePoolCapacityTypeOnDemand,
+		types.CapacityTypesSpot:     expinfrav2.ManagedMachinePoolCapacityTypeSpot,
 	}
 	ct := capacityTypes[group.CapacityType]
 
 	pool.CapacityType = &ct
 	pool.DiskSize = group.DiskSize
 	pool.EKSNodegroupName = *group.NodegroupName
-	pool.Labels = group.Labels
+
+	var warnings []error
+	pool.Labels, warnings = validateLabels(group.Labels)
+	ng.warnings = append(ng.warnings, warnings...)
 
 	for _, instance := range asg.Instances {
 		var pid string = fmt.Sprintf("aws:///%s/%s", *instance.AvailabilityZone, *instance.InstanceId)
@@ -220,7 +488,26 @@ func (f *Function) nodegroupToCapiObject(group *types.Nodegroup, ec2client AwsEc
 		}
 	}
 
-	pool.RoleName = strings.Split(*group.NodeRole, "/")[1]
+	{
+		var (
+			rolePath string
+			err      error
+		)
+		if pool.RoleName, rolePath, err = parseRoleArn(aws.ToString(group.NodeRole)); err != nil {
+			return nil, errors.Wrap(err, "invalid node role")
+		}
+
+		// CAPA only knows the role by name so keep the path where it can be seen
+		if rolePath != "/" {
+			ng.annotations[rolePathAnnotation] = rolePath
+		}
+
+		if iamclient != nil {
+			if pool.RoleAdditionalPolicies, err = getRolePolicies(pool.RoleName, iamclient); err != nil {
+				f.log.Debug("AWSAPI", "cannot list policies for role", pool.RoleName, "error", err)
+			}
+		}
+	}
 
 	if group.ScalingConfig != nil {
 		pool.Scaling = &expinfrav2.ManagedMachinePoolScaling{
@@ -230,18 +517,19 @@ func (f *Function) nodegroupToCapiObject(group *types.Nodegroup, ec2client AwsEc
 	}
 
 	pool.SubnetIDs = group.Subnets
-	for _, taint := range group.Taints {
-		t := expinfrav2.Taint{
-			Effect: expinfrav2.TaintEffect(taint.Effect),
-			Key:    *taint.Key,
-			Value:  *taint.Value,
-		}
-		pool.Taints = append(pool.Taints, t)
-	}
+	pool.Taints, warnings = mapTaints(group.Taints)
+	ng.warnings = append(ng.warnings, warnings...)
+
+	pool.AMIVersion = amiVersion(group, pool.AMIType)
 
 	pool.UpdateConfig = &expinfrav2.UpdateConfig{}
 	{
 		if group.UpdateConfig != nil {
+			// CAPA has no field for the update strategy
+			if group.UpdateConfig.UpdateStrategy != "" {
+				ng.annotations[updateStrategyAnnotation] = string(group.UpdateConfig.UpdateStrategy)
+			}
+
 			if group.UpdateConfig.MaxUnavailable != nil {
 				var max int = int(*group.UpdateConfig.MaxUnavailable)
 				pool.UpdateConfig.MaxUnavailable = &max
@@ -272,11 +560,16 @@ func getAutoscaling(name string, client AwsAsgApi, ec2client AwsEc2Api) (*asgtyp
 		return nil, nil, err
 	}
 
+	if res == nil || len(res.AutoScalingGroups) == 0 {
+		return nil, nil, fmt.Errorf("autoscaling group %q not found", name)
+	}
+
 	var (
 		autoscaling       asgtypes.AutoScalingGroup = res.AutoScalingGroups[0]
 		asglt             *asgtypes.LaunchTemplateSpecification
 		lt                types.LaunchTemplateSpecification
 		asgLaunchTemplate *expinfrav2.AWSLaunchTemplate
+		config            *LaunchTemplateConfig
 	)
 
 	if autoscaling.MixedInstancesPolicy != nil && autoscaling.MixedInstancesPolicy.LaunchTemplate != nil {
@@ -291,13 +584,15 @@ func getAutoscaling(name string, client AwsAsgApi, ec2client AwsEc2Api) (*asgtyp
 			Name:    asglt.LaunchTemplateName,
 			Version: asglt.Version,
 		}
-		asgLaunchTemplate, err = getLaunchTemplate(&lt, ec2client)
+		if config, err = getLaunchTemplate(&lt, ec2client); config != nil {
+			asgLaunchTemplate = config.template
+		}
 	}
 
 	return &autoscaling, asgLaunchTemplate, err
 }
 
-func getLaunchTemplate(base *types.LaunchTemplateSpecification, client AwsEc2Api) (*expinfrav2.AWSLaunchTemplate, error) {
+func getLaunchTemplate(base *types.LaunchTemplateSpecification, client AwsEc2Api) (*LaunchTemplateConfig, error) {
 	if base == nil {
 		// NOOP here
 		return nil, nil
@@ -306,7 +601,12 @@ func getLaunchTemplate(base *types.LaunchTemplateSpecification, client AwsEc2Api
 	var (
 		res      *ec2.DescribeLaunchTemplateVersionsOutput
 		template expinfrav2.AWSLaunchTemplate
-		err      error
+		config   LaunchTemplateConfig = LaunchTemplateConfig{
+			template:    &template,
+			tags:        make(map[string]string),
+			annotations: make(map[string]string),
+		}
+		err error
 	)
 
 	input := ec2.DescribeLaunchTemplateVersionsInput{
@@ -316,20 +616,31 @@ func getLaunchTemplate(base *types.LaunchTemplateSpecification, client AwsEc2Api
 		},
 	}
 
+	// Autoscaling groups may reference their launch template by name alone
+	if base.Id == nil {
+		input.LaunchTemplateName = base.Name
+	}
+
 	if res, err = DescribeLaunchTemplateVersions(context.TODO(), client, &input); err != nil {
 		return nil, err
 	}
 
 	if len(res.LaunchTemplateVersions) != 1 {
-		return nil, fmt.Errorf("wrong count for launch templates for template %s", *base.Name)
+		return nil, fmt.Errorf("wrong count for launch templates for template %s", aws.ToString(base.Name))
 	}
 
-	template.Name = *base.Name
+	config.id = aws.ToString(res.LaunchTemplateVersions[0].LaunchTemplateId)
+	template.Name = aws.ToString(base.Name)
+	if template.Name == "" {
+		template.Name = aws.ToString(res.LaunchTemplateVersions[0].LaunchTemplateName)
+	}
 	template.VersionNumber = res.LaunchTemplateVersions[0].VersionNumber
 
 	var data *ec2types.ResponseLaunchTemplateData = res.LaunchTemplateVersions[0].LaunchTemplateData
+	config.data = data
 	template.InstanceType = string(data.InstanceType)
 	template.SSHKeyName = data.KeyName
+	config.userData = data.UserData
 
 	template.AMI = infrav2.AMIReference{
 		ID: data.ImageId,
@@ -364,12 +675,30 @@ func getLaunchTemplate(base *types.LaunchTemplateSpecification, client AwsEc2Api
 		}
 	}
 
+	if data.MetadataOptions != nil {
+		template.InstanceMetadataOptions = &infrav2.InstanceMetadataOptions{
+			HTTPEndpoint:         infrav2.InstanceMetadataState(data.MetadataOptions.HttpEndpoint),
+			HTTPTokens:           infrav2.HTTPTokensState(data.MetadataOptions.HttpTokens),
+			InstanceMetadataTags: infrav2.InstanceMetadataState(data.MetadataOptions.InstanceMetadataTags),
+		}
+		if data.MetadataOptions.HttpPutResponseHopLimit != nil {
+			template.InstanceMetadataOptions.HTTPPutResponseHopLimit = int64(*data.MetadataOptions.HttpPutResponseHopLimit)
+		}
+	}
+
+	// Security groups may be given directly on the template or on any of
+	// the network interfaces but CAPA only holds a single list for both.
+	var groups []string = append([]string{}, data.SecurityGroupIds...)
+	for _, iface := range data.NetworkInterfaces {
+		groups = append(groups, iface.Groups...)
+	}
+
 	// This is necessary to ensure duplicate security groups are not
 	// added into the list as there is no sanitation on the AWS launch
 	// template to prevent it.
 	// AWS will simply store whatever you provide.
 	var sgs []string = make([]string, 0)
-	for _, id := range data.SecurityGroupIds {
+	for _, id := range groups {
 		var added bool = false
 		for _, v := range sgs {
 			if id == v {
@@ -388,5 +717,100 @@ func getLaunchTemplate(base *types.LaunchTemplateSpecification, client AwsEc2Api
 		})
 	}
 
-	return &template, nil
+	for _, spec := range data.TagSpecifications {
+		if spec.ResourceType == ec2types.ResourceTypeInstance {
+			for _, tag := range spec.Tags {
+				config.tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+		}
+	}
+
+	if err = launchTemplateAnnotations(data, config.annotations); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// launchTemplateAnnotations records settings from the launch template which
+// cannot be represented on the CAPA AWSLaunchTemplate as JSON encoded
+// annotations so they remain visible after the import
+func launchTemplateAnnotations(data *ec2types.ResponseLaunchTemplateData, annotations map[string]string) (err error) {
+	var settings map[string]any = make(map[string]any)
+
+	if data.Placement != nil {
+		var placement map[string]any = make(map[string]any)
+		setIfPresent(placement, "affinity", data.Placement.Affinity)
+		setIfPresent(placement, "groupId", data.Placement.GroupId)
+		setIfPresent(placement, "groupName", data.Placement.GroupName)
+		setIfPresent(placement, "hostId", data.Placement.HostId)
+		setIfPresent(placement, "hostResourceGroupArn", data.Placement.HostResourceGroupArn)
+		setIfPresent(placement, "partitionNumber", data.Placement.PartitionNumber)
+		setIfPresent(placement, "spreadDomain", data.Placement.SpreadDomain)
+		if data.Placement.Tenancy != "" {
+			placement["tenancy"] = string(data.Placement.Tenancy)
+		}
+		settings["placement"] = placement
+	}
+
+	if data.CapacityReservationSpecification != nil {
+		var reservation map[string]any = make(map[string]any)
+		if data.CapacityReservationSpecification.CapacityReservationPreference != "" {
+			reservation["preference"] = string(data.CapacityReservationSpecification.CapacityReservationPreference)
+		}
+		if target := data.CapacityReservationSpecification.CapacityReservationTarget; target != nil {
+			setIfPresent(reservation, "capacityReservationId", target.CapacityReservationId)
+			setIfPresent(reservation, "capacityReservationResourceGroupArn", target.CapacityReservationResourceGroupArn)
+		}
+		settings["capacity-reservation"] = reservation
+	}
+
+	if data.PrivateDnsNameOptions != nil {
+		var dns map[string]any = make(map[string]any)
+		setIfPresent(dns, "enableResourceNameDnsARecord", data.PrivateDnsNameOptions.EnableResourceNameDnsARecord)
+		setIfPresent(dns, "enableResourceNameDnsAAAARecord", data.PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord)
+		if data.PrivateDnsNameOptions.HostnameType != "" {
+			dns["hostnameType"] = string(data.PrivateDnsNameOptions.HostnameType)
+		}
+		settings["private-dns-name-options"] = dns
+	}
+
+	if data.MetadataOptions != nil && data.MetadataOptions.HttpProtocolIpv6 != "" {
+		settings["metadata-options"] = map[string]any{
+			"httpProtocolIpv6": string(data.MetadataOptions.HttpProtocolIpv6),
+		}
+	}
+
+	// Only instance tags have a home in CAPA. Anything tagging other resource
+	// types such as volumes or network interfaces is kept here instead.
+	var tagSpecifications map[string]map[string]string = make(map[string]map[string]string)
+	for _, spec := range data.TagSpecifications {
+		if spec.ResourceType == ec2types.ResourceTypeInstance {
+			continue
+		}
+		var tags map[string]string = make(map[string]string)
+		for _, tag := range spec.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		tagSpecifications[string(spec.ResourceType)] = tags
+	}
+	if len(tagSpecifications) > 0 {
+		settings["tag-specifications"] = tagSpecifications
+	}
+
+	for name, value := range settings {
+		var b []byte
+		if b, err = json.Marshal(value); err != nil {
+			return errors.Wrapf(err, "cannot encode launch template %s", name)
+		}
+		annotations[launchTemplateAnnotationPrefix+name] = string(b)
+	}
+	return nil
+}
+
+// setIfPresent adds the dereferenced value to the map only when it is set
+func setIfPresent[T any](m map[string]any, key string, value *T) {
+	if value != nil {
+		m[key] = *value
+	}
 }
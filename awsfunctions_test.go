@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/google/go-cmp/cmp"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+func TestNodegroupToCapiObject(t *testing.T) {
+	nodegroup := func(capacityType types.CapacityTypes, resources *types.NodegroupResources) *types.Nodegroup {
+		return &types.Nodegroup{
+			AmiType:       types.AMITypesAl2X8664,
+			CapacityType:  capacityType,
+			ClusterName:   aws.String("test"),
+			InstanceTypes: []string{"m5.large"},
+			NodeRole:      aws.String("arn:aws:iam::123456789012:role/node-role"),
+			NodegroupName: aws.String("ng-12345"),
+			Resources:     resources,
+		}
+	}
+
+	cases := map[string]struct {
+		group            *types.Nodegroup
+		asg              AwsAsgApi
+		wantCapacityType expinfrav2.ManagedMachinePoolCapacityType
+		wantErr          bool
+	}{
+		"on-demand nodegroup": {
+			group: nodegroup(types.CapacityTypesOnDemand, &types.NodegroupResources{
+				AutoScalingGroups: []types.AutoScalingGroup{{Name: aws.String("asg-12345")}},
+			}),
+			asg:              &ValidAsgMock{},
+			wantCapacityType: expinfrav2.ManagedMachinePoolCapacityTypeOnDemand,
+		},
+		"spot nodegroup": {
+			group: nodegroup(types.CapacityTypesSpot, &types.NodegroupResources{
+				AutoScalingGroups: []types.AutoScalingGroup{{Name: aws.String("asg-12345")}},
+			}),
+			asg:              &ValidAsgMock{},
+			wantCapacityType: expinfrav2.ManagedMachinePoolCapacityTypeSpot,
+		},
+		"nodegroup still being created": {
+			group:   nodegroup(types.CapacityTypesOnDemand, &types.NodegroupResources{}),
+			asg:     &EmptyAsgMock{},
+			wantErr: true,
+		},
+		"nodegroup without resources": {
+			group:   nodegroup(types.CapacityTypesOnDemand, nil),
+			asg:     &EmptyAsgMock{},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger()}
+			filter, _ := newTagFilter(nil)
+
+			ng, err := f.nodegroupToCapiObject(tc.group, &ValidEc2Mock{}, tc.asg, nil, filter)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("nodegroupToCapiObject(...): unexpected error state: %v", err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(tc.wantCapacityType, *ng.spec.CapacityType); diff != "" {
+				t.Errorf("nodegroupToCapiObject(...): capacity type -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
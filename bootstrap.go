@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	eksbootstrapv1 "sigs.k8s.io/cluster-api-provider-aws/v2/bootstrap/eks/api/v1beta2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// bootstrapScript is the path to the AL2 bootstrap script
+	bootstrapScript = "/etc/eks/bootstrap.sh"
+
+	// redacted is the value written in place of anything that looks like a
+	// secret in the user data
+	redacted = "<redacted>"
+
+	// the multipart content type used by nodeadm on AL2023
+	nodeadmContentType = "application/node.eks.aws"
+
+	// bottlerocketSettingsAnnotation holds the Bottlerocket settings from the
+	// user data as JSON, as no EKSConfig can carry them
+	bottlerocketSettingsAnnotation = "describenodegroups.fn.giantswarm.io/bottlerocket-settings"
+)
+
+var (
+	// secretAssignmentPattern matches name=value assignments whose name
+	// suggests the value holds a credential
+	secretAssignmentPattern = regexp.MustCompile(`(?i)([\w.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)[\w.-]*\s*=\s*)("[^"]*"|'[^']*'|[^\s"']+)`)
+
+	// secretFlagPattern matches command line flags known to take a
+	// credential as their next argument
+	secretFlagPattern = regexp.MustCompile(`(?i)((?:^|\s)--(?:password|passwd|token|secret|client-secret|secret-access-key|aws-secret-access-key|api-key|access-token|auth-token|bearer-token|registry-password)\s+)("[^"]*"|'[^']*'|[^\s"']+)`)
+
+	// documentSeparator matches the line separating YAML documents
+	documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+	// assignmentPattern matches a simple shell variable assignment
+	assignmentPattern = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+	// variablePattern matches shell variable references
+	variablePattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+
+	// bootstrapSecretArgs are bootstrap.sh arguments that must never be
+	// copied into the EKSConfig
+	bootstrapSecretArgs = map[string]bool{
+		"docker-config-json": true,
+	}
+
+	// bootstrapClusterArgs are bootstrap.sh arguments CABPK derives from the
+	// cluster itself and which therefore are not carried over
+	bootstrapClusterArgs = map[string]bool{
+		"b64-cluster-ca":       true,
+		"apiserver-endpoint":   true,
+		"enable-docker-bridge": true,
+	}
+)
+
+// userDataFormat is the format of the user data given to a launch template
+type userDataFormat string
+
+const (
+	userDataMultipart    userDataFormat = "MIME multipart"
+	userDataShellScript  userDataFormat = "shell script"
+	userDataNodeConfig   userDataFormat = "nodeadm NodeConfig"
+	userDataBottlerocket userDataFormat = "Bottlerocket TOML"
+)
+
+// userDataFormats lists the user data formats each AMI family boots from.
+// Families not listed here accept any format.
+var userDataFormats map[amiFamily][]userDataFormat = map[amiFamily][]userDataFormat{
+	amiFamilyAmazonLinux2:    {userDataMultipart, userDataShellScript},
+	amiFamilyAmazonLinux2023: {userDataMultipart, userDataNodeConfig},
+	amiFamilyBottlerocket:    {userDataBottlerocket},
+}
+
+// parseUserData decodes the user data from a launch template and maps any
+// bootstrap configuration found into an EKSConfigSpec
+//
+// User data may be a plain shell script calling the AL2 bootstrap.sh, a MIME
+// multipart document holding shell scripts and AL2023 nodeadm NodeConfig
+// documents, or Bottlerocket TOML settings. Where the AMI family is known the
+// format must be one that family boots from. Windows nodes bootstrap through
+// PowerShell which the EKSConfig cannot describe.
+//
+// CABPK renders every EKSConfig as AL2 user data, so Bottlerocket settings are
+// recorded in the given annotations and no spec is returned for them.
+func parseUserData(encoded string, family amiFamily, annotations map[string]string) (spec *eksbootstrapv1.EKSConfigSpec, warnings []error, err error) {
+	if family == amiFamilyWindows {
+		return nil, nil, fmt.Errorf("bootstrap configuration of %s nodes cannot be held in an EKSConfig", family)
+	}
+
+	var data []byte
+	if data, err = decodeUserData(encoded); err != nil {
+		return nil, nil, err
+	}
+
+	var trimmed string = strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return nil, nil, nil
+	}
+
+	var format userDataFormat
+	if format, err = detectUserDataFormat(trimmed); err != nil {
+		return nil, nil, err
+	}
+
+	if formats, ok := userDataFormats[family]; ok && !slices.Contains(formats, format) {
+		return nil, nil, fmt.Errorf("%s user data cannot bootstrap %s nodes", format, family)
+	}
+
+	if format == userDataBottlerocket {
+		var settings string
+		if settings, err = bottlerocketSettings(data); err != nil {
+			return nil, nil, err
+		}
+		annotations[bottlerocketSettingsAnnotation] = settings
+		return nil, []error{fmt.Errorf("%s user data cannot be held in an EKSConfig as CABPK would render it as AL2 user data, the settings are recorded in the %s annotation", format, bottlerocketSettingsAnnotation)}, nil
+	}
+
+	spec = &eksbootstrapv1.EKSConfigSpec{}
+	switch format {
+	case userDataMultipart:
+		warnings, err = parseMultipart(data, spec)
+	case userDataShellScript:
+		warnings = parseShellScript(trimmed, spec)
+	case userDataNodeConfig:
+		err = parseNodeConfig(data, spec)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec, warnings, nil
+}
+
+// decodeUserData base64 decodes the user data and unpacks it if compressed
+func decodeUserData(encoded string) (data []byte, err error) {
+	if data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded)); err != nil {
+		return nil, errors.Wrap(err, "cannot decode user data")
+	}
+
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return nil, errors.Wrap(err, "cannot decompress user data")
+		}
+		defer r.Close()
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, errors.Wrap(err, "cannot decompress user data")
+		}
+	}
+	return data, nil
+}
+
+// detectUserDataFormat works out the format of decoded user data
+func detectUserDataFormat(data string) (userDataFormat, error) {
+	switch {
+	case isMultipart(data):
+		return userDataMultipart, nil
+	case strings.HasPrefix(data, "#!") || strings.Contains(data, bootstrapScript):
+		return userDataShellScript, nil
+	case strings.Contains(data, "kind: NodeConfig"):
+		return userDataNodeConfig, nil
+	case strings.HasPrefix(data, "[settings"):
+		return userDataBottlerocket, nil
+	}
+	return "", fmt.Errorf("unrecognised user data format")
+}
+
+func isMultipart(data string) bool {
+	var header string = strings.ToLower(strings.SplitN(data, "\n\n", 2)[0])
+	return strings.HasPrefix(header, "mime-version:") || strings.Contains(header, "content-type: multipart/")
+}
+
+// parseMultipart walks each part of a MIME multipart user data document
+func parseMultipart(data []byte, spec *eksbootstrapv1.EKSConfigSpec) (warnings []error, err error) {
+	var msg *mail.Message
+	if msg, err = mail.ReadMessage(bytes.NewReader(data)); err != nil {
+		return nil, errors.Wrap(err, "cannot read multipart user data")
+	}
+
+	var (
+		mediaType string
+		params    map[string]string
+	)
+	if mediaType, params, err = mime.ParseMediaType(msg.Header.Get("Content-Type")); err != nil {
+		return nil, errors.Wrap(err, "cannot parse user data content type")
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("unexpected user data content type %q", mediaType)
+	}
+
+	var reader *multipart.Reader = multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		var part *multipart.Part
+		if part, err = reader.NextPart(); err == io.EOF {
+			return warnings, nil
+		} else if err != nil {
+			return nil, errors.Wrap(err, "cannot read user data part")
+		}
+
+		var body []byte
+		if body, err = io.ReadAll(part); err != nil {
+			return nil, errors.Wrap(err, "cannot read user data part")
+		}
+
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			if body, err = base64.StdEncoding.DecodeString(string(body)); err != nil {
+				return nil, errors.Wrap(err, "cannot decode user data part")
+			}
+		}
+
+		var partType string
+		if partType, _, err = mime.ParseMediaType(part.Header.Get("Content-Type")); err != nil {
+			return nil, errors.Wrap(err, "cannot parse user data part content type")
+		}
+
+		switch partType {
+		case "text/x-shellscript":
+			warnings = append(warnings, parseShellScript(strings.TrimSpace(string(body)), spec)...)
+		case nodeadmContentType:
+			if err = parseNodeConfig(body, spec); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// parseShellScript extracts the arguments given to bootstrap.sh and keeps any
+// other commands as pre or post bootstrap commands. Commands which had a
+// credential redacted are returned as warnings since they no longer run as
+// they did in the user data.
+func parseShellScript(script string, spec *eksbootstrapv1.EKSConfigSpec) (warnings []error) {
+	var (
+		lines      []string          = joinContinuations(script)
+		variables  map[string]string = make(map[string]string)
+		consumed   map[string]bool   = make(map[string]bool)
+		referenced map[string]bool   = make(map[string]bool)
+		commands   []string
+		before     bool = true
+	)
+
+	for _, line := range lines {
+		if m := assignmentPattern.FindStringSubmatch(line); m != nil {
+			var words []string = splitWords(m[2])
+			if len(words) == 1 {
+				variables[m[1]] = words[0]
+			}
+		}
+	}
+
+	for _, line := range lines {
+		if !strings.Contains(line, bootstrapScript) {
+			// Variables the remaining commands use must keep their assignment
+			for _, m := range variablePattern.FindAllStringSubmatch(line, -1) {
+				referenced[m[1]] = true
+			}
+			continue
+		}
+		for _, m := range variablePattern.FindAllStringSubmatch(line, -1) {
+			consumed[m[1]] = true
+		}
+		var args []string = splitWords(expandVariables(line, variables))
+		for i, arg := range args {
+			if strings.HasSuffix(arg, bootstrapScript) {
+				parseBootstrapArgs(args[i+1:], spec)
+				break
+			}
+		}
+	}
+
+	for _, line := range lines {
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.Contains(line, bootstrapScript):
+			spec.PreBootstrapCommands = append(spec.PreBootstrapCommands, commands...)
+			commands = nil
+			before = false
+			continue
+		}
+
+		if m := assignmentPattern.FindStringSubmatch(line); m != nil && consumed[m[1]] && !referenced[m[1]] {
+			continue
+		}
+
+		var command string = redact(line)
+		if command != line {
+			warnings = append(warnings, fmt.Errorf("credential redacted from bootstrap command %q, set the value with a patch for the command to run as before", command))
+		}
+		commands = append(commands, command)
+	}
+
+	if before {
+		spec.PreBootstrapCommands = append(spec.PreBootstrapCommands, commands...)
+	} else {
+		spec.PostBootstrapCommands = append(spec.PostBootstrapCommands, commands...)
+	}
+	return warnings
+}
+
+// parseBootstrapArgs maps the arguments of the bootstrap.sh call onto the spec
+func parseBootstrapArgs(args []string, spec *eksbootstrapv1.EKSConfigSpec) {
+	var (
+		pauseAccount, pauseVersion string
+		i                          int
+	)
+	for i = 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			// The cluster name is positional and comes from CAPI
+			continue
+		}
+
+		// Every bootstrap.sh option takes a value, which for the kubelet
+		// extra args will itself start with dashes.
+		var name, value string = strings.TrimPrefix(args[i], "--"), ""
+		if before, after, ok := strings.Cut(name, "="); ok {
+			name, value = before, after
+		} else if i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+
+		switch {
+		case bootstrapSecretArgs[name], bootstrapClusterArgs[name]:
+			continue
+		case name == "kubelet-extra-args":
+			for k, v := range parseFlags(splitWords(value)) {
+				if spec.KubeletExtraArgs == nil {
+					spec.KubeletExtraArgs = make(map[string]string)
+				}
+				spec.KubeletExtraArgs[k] = v
+			}
+		case name == "container-runtime":
+			spec.ContainerRuntime = &value
+		case name == "dns-cluster-ip":
+			spec.DNSClusterIP = &value
+		case name == "service-ipv6-cidr":
+			spec.ServiceIPV6Cidr = &value
+		case name == "use-max-pods":
+			if b, err := strconv.ParseBool(value); err == nil {
+				spec.UseMaxPods = &b
+			}
+		case name == "aws-api-retry-attempts":
+			if n, err := strconv.Atoi(value); err == nil {
+				spec.APIRetryAttempts = &n
+			}
+		case name == "pause-container-account":
+			pauseAccount = value
+		case name == "pause-container-version":
+			pauseVersion = value
+		}
+	}
+
+	if pauseAccount != "" || pauseVersion != "" {
+		spec.PauseContainer = &eksbootstrapv1.PauseContainer{
+			AccountNumber: pauseAccount,
+			Version:       pauseVersion,
+		}
+	}
+}
+
+// nodeConfig is the subset of the nodeadm NodeConfig this function reads
+type nodeConfig struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Kubelet struct {
+			Config map[string]any `json:"config"`
+			Flags  []string       `json:"flags"`
+		} `json:"kubelet"`
+		Containerd struct {
+			Config string `json:"config"`
+		} `json:"containerd"`
+	} `json:"spec"`
+}
+
+// parseNodeConfig maps the kubelet and containerd settings from one or more
+// AL2023 nodeadm NodeConfig documents onto the spec
+func parseNodeConfig(data []byte, spec *eksbootstrapv1.EKSConfigSpec) (err error) {
+	for _, document := range documentSeparator.Split(string(data), -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+
+		var config nodeConfig
+		if err = yaml.Unmarshal([]byte(document), &config); err != nil {
+			return errors.Wrap(err, "cannot parse NodeConfig")
+		}
+
+		if config.Kind != "NodeConfig" {
+			continue
+		}
+
+		var args map[string]string = parseFlags(config.Spec.Kubelet.Flags)
+		if maxPods, ok := config.Spec.Kubelet.Config["maxPods"]; ok {
+			args["max-pods"] = fmt.Sprint(maxPods)
+		}
+
+		if dns, ok := config.Spec.Kubelet.Config["clusterDNS"].([]any); ok && len(dns) > 0 {
+			var ip string = fmt.Sprint(dns[0])
+			spec.DNSClusterIP = &ip
+		}
+
+		for k, v := range args {
+			if spec.KubeletExtraArgs == nil {
+				spec.KubeletExtraArgs = make(map[string]string)
+			}
+			spec.KubeletExtraArgs[k] = v
+		}
+
+		if config.Spec.Containerd.Config != "" {
+			var runtime string = "containerd"
+			spec.ContainerRuntime = &runtime
+		}
+	}
+	return nil
+}
+
+// bottlerocketSettings renders the settings of Bottlerocket TOML user data as
+// JSON with any credential redacted
+func bottlerocketSettings(data []byte) (settings string, err error) {
+	var document struct {
+		Settings map[string]any `toml:"settings" json:"settings"`
+	}
+
+	if _, err = toml.Decode(string(data), &document); err != nil {
+		return "", errors.Wrap(err, "cannot parse bottlerocket settings")
+	}
+
+	redactSettings(document.Settings)
+
+	var b []byte
+	if b, err = json.Marshal(document); err != nil {
+		return "", errors.Wrap(err, "cannot marshal bottlerocket settings")
+	}
+	return string(b), nil
+}
+
+// redactSettings replaces the value of every setting whose name suggests it
+// holds a credential, such as container registry passwords
+func redactSettings(settings any) {
+	switch v := settings.(type) {
+	case map[string]any:
+		for name, value := range v {
+			if name == "auth" || secretAssignmentPattern.MatchString(name+"=value") {
+				v[name] = redacted
+				continue
+			}
+			redactSettings(value)
+		}
+	case []map[string]any:
+		for _, value := range v {
+			redactSettings(value)
+		}
+	case []any:
+		for _, value := range v {
+			redactSettings(value)
+		}
+	}
+}
+
+// parseFlags converts a list of `--flag=value` or `--flag value` arguments
+// into a map keyed by flag name. Values that look like secrets are redacted.
+func parseFlags(flags []string) map[string]string {
+	var args map[string]string = make(map[string]string)
+	for i := 0; i < len(flags); i++ {
+		if !strings.HasPrefix(flags[i], "-") {
+			continue
+		}
+
+		var name, value string = strings.TrimLeft(flags[i], "-"), ""
+		if before, after, ok := strings.Cut(name, "="); ok {
+			name, value = before, after
+		} else if i+1 < len(flags) && !strings.HasPrefix(flags[i+1], "-") {
+			i++
+			value = flags[i]
+		}
+
+		if name == "" {
+			continue
+		}
+
+		if secretAssignmentPattern.MatchString(name + "=" + value) {
+			value = redacted
+		}
+		args[name] = value
+	}
+	return args
+}
+
+// joinContinuations returns the lines of a script with backslash-newline
+// continuations folded into a single line
+func joinContinuations(script string) (lines []string) {
+	var (
+		scanner *bufio.Scanner = bufio.NewScanner(strings.NewReader(script))
+		current string
+	)
+	for scanner.Scan() {
+		var line string = strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(current+line))
+		current = ""
+	}
+	if current != "" {
+		lines = append(lines, strings.TrimSpace(current))
+	}
+	return
+}
+
+// splitWords splits a shell command line into words honouring single and
+// double quotes and backslash escapes
+func splitWords(line string) (words []string) {
+	var (
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return
+}
+
+// expandVariables replaces references to variables assigned in the script
+// with their value
+func expandVariables(line string, variables map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(line, func(ref string) string {
+		var name string = variablePattern.FindStringSubmatch(ref)[1]
+		if v, ok := variables[name]; ok {
+			return v
+		}
+		return ref
+	})
+}
+
+// redact replaces the value of credential assignments and flags in a command.
+// Values taken from variables or command substitution are left alone as they
+// hold no credential themselves.
+func redact(line string) string {
+	for _, pattern := range []*regexp.Regexp{secretAssignmentPattern, secretFlagPattern} {
+		var (
+			redactedLine strings.Builder
+			last         int
+		)
+		for _, m := range pattern.FindAllStringSubmatchIndex(line, -1) {
+			if strings.Contains(line[m[4]:m[5]], "$") {
+				continue
+			}
+			redactedLine.WriteString(line[last:m[4]])
+			redactedLine.WriteString(redacted)
+			last = m[5]
+		}
+		redactedLine.WriteString(line[last:])
+		line = redactedLine.String()
+	}
+	return line
+}
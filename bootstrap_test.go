@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	eksbootstrapv1 "sigs.k8s.io/cluster-api-provider-aws/v2/bootstrap/eks/api/v1beta2"
+)
+
+var (
+	al2UserData = `#!/bin/bash
+set -ex
+export HTTPS_PROXY=http://proxy.example.com:3128
+export GITHUB_TOKEN=ghp_abcdef
+B64_CLUSTER_CA=LS0tLS1CRUdJTg==
+API_SERVER_URL=https://ABCDEF.gr7.eu-central-1.eks.amazonaws.com
+K8S_CLUSTER_DNS_IP=172.20.0.10
+/etc/eks/bootstrap.sh example \
+  --kubelet-extra-args '--node-labels=role=worker --max-pods=58' \
+  --b64-cluster-ca $B64_CLUSTER_CA --apiserver-endpoint $API_SERVER_URL \
+  --dns-cluster-ip $K8S_CLUSTER_DNS_IP --use-max-pods false \
+  --container-runtime containerd --docker-config-json '{"auths":{}}'
+echo "done"
+`
+
+	al2023UserData = `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: application/node.eks.aws
+
+---
+apiVersion: node.eks.aws/v1alpha1
+kind: NodeConfig
+spec:
+  cluster:
+    name: example
+  kubelet:
+    config:
+      maxPods: 110
+      clusterDNS:
+      - 172.20.0.10
+    flags:
+    - --node-labels=role=worker
+  containerd:
+    config: |
+      [plugins."io.containerd.grpc.v1.cri".containerd]
+      discard_unpacked_layers = false
+
+--BOUNDARY
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+echo "password=hunter2" > /tmp/config
+
+--BOUNDARY--
+`
+
+	windowsUserData = `<powershell>
+[string]$EKSBootstrapScriptFile = "$env:ProgramFiles\Amazon\EKS\Start-EKSBootstrap.ps1"
+& $EKSBootstrapScriptFile -EKSClusterName "example" 3>&1 4>&1 5>&1 6>&1
+</powershell>
+`
+
+	bottlerocketUserData = `[settings.kubernetes]
+cluster-name = "example"
+api-server = "https://ABCDEF.gr7.eu-central-1.eks.amazonaws.com"
+max-pods = 29
+cluster-dns-ip = "172.20.0.10"
+
+[settings.kubernetes.node-labels]
+"role" = "worker"
+"team" = "honeybadger"
+
+[settings.container-runtime]
+max-concurrent-downloads = 3
+
+[[settings.container-registry.credentials]]
+registry = "docker.io"
+username = "honeybadger"
+password = "hunter2"
+`
+)
+
+func TestParseUserData(t *testing.T) {
+	cases := map[string]struct {
+		userData    string
+		family      amiFamily
+		want        *eksbootstrapv1.EKSConfigSpec
+		warnings    []string
+		annotations map[string]string
+		wantErr     bool
+	}{
+		"AL2 bootstrap script": {
+			userData: al2UserData,
+			family:   amiFamilyAmazonLinux2,
+			want: &eksbootstrapv1.EKSConfigSpec{
+				KubeletExtraArgs: map[string]string{
+					"node-labels": "role=worker",
+					"max-pods":    "58",
+				},
+				ContainerRuntime: aws.String("containerd"),
+				DNSClusterIP:     aws.String("172.20.0.10"),
+				UseMaxPods:       aws.Bool(false),
+				PreBootstrapCommands: []string{
+					"set -ex",
+					"export HTTPS_PROXY=http://proxy.example.com:3128",
+					"export GITHUB_TOKEN=<redacted>",
+				},
+				PostBootstrapCommands: []string{
+					`echo "done"`,
+				},
+			},
+			warnings: []string{
+				`credential redacted from bootstrap command "export GITHUB_TOKEN=<redacted>", set the value with a patch for the command to run as before`,
+			},
+		},
+		"AL2023 nodeadm multipart": {
+			userData: al2023UserData,
+			family:   amiFamilyAmazonLinux2023,
+			want: &eksbootstrapv1.EKSConfigSpec{
+				KubeletExtraArgs: map[string]string{
+					"node-labels": "role=worker",
+					"max-pods":    "110",
+				},
+				ContainerRuntime: aws.String("containerd"),
+				DNSClusterIP:     aws.String("172.20.0.10"),
+				PreBootstrapCommands: []string{
+					`echo "password=<redacted>" > /tmp/config`,
+				},
+			},
+			warnings: []string{
+				`credential redacted from bootstrap command "echo \"password=<redacted>\" > /tmp/config", set the value with a patch for the command to run as before`,
+			},
+		},
+		"commands without credentials": {
+			userData: `#!/bin/bash
+TOKEN=$(curl -s -X PUT http://169.254.169.254/latest/api/token)
+REGISTRY_PASSWORD=$(aws secretsmanager get-secret-value --secret-id registry --query SecretString --output text)
+DNS_IP=172.20.0.10
+/etc/eks/bootstrap.sh example --dns-cluster-ip $DNS_IP
+echo "$DNS_IP" > /etc/dns-ip
+registry-login --password $REGISTRY_PASSWORD
+`,
+			family: amiFamilyAmazonLinux2,
+			want: &eksbootstrapv1.EKSConfigSpec{
+				DNSClusterIP: aws.String("172.20.0.10"),
+				PreBootstrapCommands: []string{
+					"TOKEN=$(curl -s -X PUT http://169.254.169.254/latest/api/token)",
+					"REGISTRY_PASSWORD=$(aws secretsmanager get-secret-value --secret-id registry --query SecretString --output text)",
+					"DNS_IP=172.20.0.10",
+				},
+				PostBootstrapCommands: []string{
+					`echo "$DNS_IP" > /etc/dns-ip`,
+					"registry-login --password $REGISTRY_PASSWORD",
+				},
+			},
+		},
+		"credential flag": {
+			userData: "#!/bin/bash\nregistry-login --username admin --password hunter2\n/etc/eks/bootstrap.sh example\n",
+			family:   amiFamilyAmazonLinux2,
+			want: &eksbootstrapv1.EKSConfigSpec{
+				PreBootstrapCommands: []string{
+					"registry-login --username admin --password <redacted>",
+				},
+			},
+			warnings: []string{
+				`credential redacted from bootstrap command "registry-login --username admin --password <redacted>", set the value with a patch for the command to run as before`,
+			},
+		},
+		"Bottlerocket settings": {
+			userData: bottlerocketUserData,
+			family:   amiFamilyBottlerocket,
+			warnings: []string{
+				"Bottlerocket TOML user data cannot be held in an EKSConfig as CABPK would render it as AL2 user data, the settings are recorded in the describenodegroups.fn.giantswarm.io/bottlerocket-settings annotation",
+			},
+			annotations: map[string]string{
+				bottlerocketSettingsAnnotation: `{"settings":{"container-registry":{"credentials":"\u003credacted\u003e"},"container-runtime":{"max-concurrent-downloads":3},"kubernetes":{"api-server":"https://ABCDEF.gr7.eu-central-1.eks.amazonaws.com","cluster-dns-ip":"172.20.0.10","cluster-name":"example","max-pods":29,"node-labels":{"role":"worker","team":"honeybadger"}}}}`,
+			},
+		},
+		"custom AMI with bootstrap script": {
+			userData: "#!/bin/bash\n/etc/eks/bootstrap.sh example --container-runtime containerd\n",
+			family:   amiFamilyCustom,
+			want: &eksbootstrapv1.EKSConfigSpec{
+				ContainerRuntime: aws.String("containerd"),
+			},
+		},
+		"unknown family": {
+			userData: bottlerocketUserData,
+			warnings: []string{
+				"Bottlerocket TOML user data cannot be held in an EKSConfig as CABPK would render it as AL2 user data, the settings are recorded in the describenodegroups.fn.giantswarm.io/bottlerocket-settings annotation",
+			},
+			annotations: map[string]string{
+				bottlerocketSettingsAnnotation: `{"settings":{"container-registry":{"credentials":"\u003credacted\u003e"},"container-runtime":{"max-concurrent-downloads":3},"kubernetes":{"api-server":"https://ABCDEF.gr7.eu-central-1.eks.amazonaws.com","cluster-dns-ip":"172.20.0.10","cluster-name":"example","max-pods":29,"node-labels":{"role":"worker","team":"honeybadger"}}}}`,
+			},
+		},
+		"AL2 script on Bottlerocket": {
+			userData: al2UserData,
+			family:   amiFamilyBottlerocket,
+			wantErr:  true,
+		},
+		"Bottlerocket settings on AL2023": {
+			userData: bottlerocketUserData,
+			family:   amiFamilyAmazonLinux2023,
+			wantErr:  true,
+		},
+		"Windows": {
+			userData: windowsUserData,
+			family:   amiFamilyWindows,
+			wantErr:  true,
+		},
+		"unrecognised format": {
+			userData: "just some text",
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var annotations map[string]string = make(map[string]string)
+			got, warnings, err := parseUserData(base64.StdEncoding.EncodeToString([]byte(tc.userData)), tc.family, annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseUserData(...): unexpected error state: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseUserData(...): -want, +got:\n%s", diff)
+			}
+
+			var messages []string
+			for _, w := range warnings {
+				messages = append(messages, w.Error())
+			}
+			if diff := cmp.Diff(tc.warnings, messages); diff != "" {
+				t.Errorf("parseUserData(...): warnings -want, +got:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.annotations, annotations, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("parseUserData(...): annotations -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	ekscontrolplanev2 "sigs.k8s.io/cluster-api-provider-aws/v2/controlplane/eks/api/v1beta2"
+	capiinfra "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// securityGroupsAnnotation records the additional security groups of the
+	// control plane which CAPA has no field for
+	securityGroupsAnnotation = "describenodegroups.fn.giantswarm.io/security-groups"
+
+	// identityProviderType is the only identity provider type supported by EKS
+	identityProviderType = "oidc"
+)
+
+// controlPlaneLogTypes maps the EKS log types onto the CAPA logging setting
+var controlPlaneLogTypes map[types.LogType]func(*ekscontrolplanev2.ControlPlaneLoggingSpec) *bool = map[types.LogType]func(*ekscontrolplanev2.ControlPlaneLoggingSpec) *bool{
+	types.LogTypeApi:               func(l *ekscontrolplanev2.ControlPlaneLoggingSpec) *bool { return &l.APIServer },
+	types.LogTypeAudit:             func(l *ekscontrolplanev2.ControlPlaneLoggingSpec) *bool { return &l.Audit },
+	types.LogTypeAuthenticator:     func(l *ekscontrolplanev2.ControlPlaneLoggingSpec) *bool { return &l.Authenticator },
+	types.LogTypeControllerManager: func(l *ekscontrolplanev2.ControlPlaneLoggingSpec) *bool { return &l.ControllerManager },
+	types.LogTypeScheduler:         func(l *ekscontrolplanev2.ControlPlaneLoggingSpec) *bool { return &l.Scheduler },
+}
+
+// importControlPlane describes the EKS cluster and adds the Cluster,
+// AWSManagedControlPlane and AWSManagedCluster representing it
+//
+// Failing to import the control plane leaves the nodegroups unaffected, so
+// every failure is reported as a warning and the remaining pools are still
+// imported.
+func (f *Function) importControlPlane(ac *XrConfig, eksclient AwsEksApi, iamclient AwsIamApi, filter *tagFilter) {
+	var (
+		res *eks.DescribeClusterOutput
+		err error
+	)
+	if res, err = DescribeCluster(context.TODO(), eksclient, &eks.DescribeClusterInput{
+		Name: ac.cluster,
+	}); err != nil {
+		ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot describe cluster %q, the control plane is not imported", *ac.cluster))
+		return
+	}
+
+	if res == nil || res.Cluster == nil {
+		ac.warnings = append(ac.warnings, fmt.Errorf("cluster %q not found, the control plane is not imported", *ac.cluster))
+		return
+	}
+
+	var (
+		cluster     *types.Cluster = res.Cluster
+		spec        *ekscontrolplanev2.AWSManagedControlPlaneSpec
+		annotations map[string]string = newAnnotations(ac, "AWSManagedControlPlane")
+	)
+
+	if spec, err = clusterToCapiObject(cluster, *ac.region, filter, annotations); err != nil {
+		ac.warnings = append(ac.warnings, errors.Wrapf(err, "cluster %q cannot be imported", *ac.cluster))
+		return
+	}
+
+	if cluster.Identity != nil && cluster.Identity.Oidc != nil && cluster.Identity.Oidc.Issuer != nil {
+		if spec.AssociateOIDCProvider, err = hasOIDCProvider(*cluster.Identity.Oidc.Issuer, iamclient); err != nil {
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot look up the IAM OIDC provider of cluster %q", *ac.cluster))
+		}
+	}
+
+	var warnings, addonWarnings []error
+	spec.OIDCIdentityProviderConfig, warnings = getIdentityProviderConfig(*ac.cluster, eksclient, filter)
+	spec.Addons, addonWarnings = getAddons(*ac.cluster, eksclient)
+	for _, warning := range append(warnings, addonWarnings...) {
+		ac.warnings = append(ac.warnings, errors.Wrapf(warning, "cluster %q", *ac.cluster))
+	}
+
+	var (
+		ready            bool   = cluster.Status == types.ClusterStatusActive
+		controlPlaneName string = objectName(ac, "awsmanagedcontrolplane", "")
+		infraName        string = objectName(ac, "awsmanagedcluster", "")
+	)
+	f.log.Info("AWSAPI", "Creating control plane", controlPlaneName)
+
+	var controlPlane ekscontrolplanev2.AWSManagedControlPlane = ekscontrolplanev2.AWSManagedControlPlane{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AWSManagedControlPlane",
+			APIVersion: "controlplane.cluster.x-k8s.io/v1beta2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        controlPlaneName,
+			Namespace:   *ac.namespace,
+			Labels:      newLabels(ac, "AWSManagedControlPlane", ""),
+			Annotations: annotations,
+		},
+		Spec: *spec,
+		Status: ekscontrolplanev2.AWSManagedControlPlaneStatus{
+			Initialized: ready,
+			Ready:       ready,
+		},
+	}
+
+	var infraCluster infrav2.AWSManagedCluster = infrav2.AWSManagedCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AWSManagedCluster",
+			APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        infraName,
+			Namespace:   *ac.namespace,
+			Labels:      newLabels(ac, "AWSManagedCluster", ""),
+			Annotations: newAnnotations(ac, "AWSManagedCluster"),
+		},
+		Spec: infrav2.AWSManagedClusterSpec{
+			ControlPlaneEndpoint: spec.ControlPlaneEndpoint,
+		},
+		Status: infrav2.AWSManagedClusterStatus{
+			Ready: ready,
+		},
+	}
+
+	// Unless the name template says otherwise, the Cluster carries the name of
+	// the EKS cluster. Every MachinePool refers to it by that name.
+	var capiCluster capiinfra.Cluster = capiinfra.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Cluster",
+			APIVersion: "cluster.x-k8s.io/v1beta1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        clusterName(ac),
+			Namespace:   *ac.namespace,
+			Labels:      newLabels(ac, "Cluster", ""),
+			Annotations: newAnnotations(ac, "Cluster"),
+		},
+		Spec: capiinfra.ClusterSpec{
+			ControlPlaneEndpoint: spec.ControlPlaneEndpoint,
+			ControlPlaneRef: &v1.ObjectReference{
+				Kind:       "AWSManagedControlPlane",
+				APIVersion: "controlplane.cluster.x-k8s.io/v1beta2",
+				Namespace:  *ac.namespace,
+				Name:       controlPlaneName,
+			},
+			InfrastructureRef: &v1.ObjectReference{
+				Kind:       "AWSManagedCluster",
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+				Namespace:  *ac.namespace,
+				Name:       infraName,
+			},
+		},
+		Status: capiinfra.ClusterStatus{
+			ControlPlaneReady:   ready,
+			InfrastructureReady: ready,
+		},
+	}
+
+	if network := cluster.KubernetesNetworkConfig; network != nil {
+		var cidr *string = network.ServiceIpv4Cidr
+		if network.IpFamily == types.IpFamilyIpv6 {
+			cidr = network.ServiceIpv6Cidr
+		}
+
+		if cidr != nil {
+			capiCluster.Spec.ClusterNetwork = &capiinfra.ClusterNetwork{
+				Services: &capiinfra.NetworkRanges{
+					CIDRBlocks: []string{*cidr},
+				},
+			}
+		}
+	}
+
+	// The identity provider and add-ons are only kept in their mapped form
+	var source provenance = provenance{
+		arn:  aws.ToString(cluster.Arn),
+		hash: sourceHash(clusterSource(cluster), spec.OIDCIdentityProviderConfig, spec.Addons),
+	}
+
+	// A Cluster is of no use without the objects it references, so the
+	// remaining objects are skipped once one fails to be added
+	for _, object := range []struct {
+		name   string
+		object any
+	}{
+		{controlPlaneName, controlPlane},
+		{infraName, infraCluster},
+		{clusterName(ac), capiCluster},
+	} {
+		if err = f.addDesired(ac, object.name, object.object, source); err != nil {
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "control plane of cluster %q is incomplete, %q cannot be added", *ac.cluster, object.name))
+			return
+		}
+	}
+}
+
+// clusterToCapiObject maps an EKS cluster onto the CAPA control plane spec.
+// Settings CAPA has no field for are added to annotations.
+func clusterToCapiObject(cluster *types.Cluster, region string, filter *tagFilter, annotations map[string]string) (spec *ekscontrolplanev2.AWSManagedControlPlaneSpec, err error) {
+	spec = &ekscontrolplanev2.AWSManagedControlPlaneSpec{
+		EKSClusterName: aws.ToString(cluster.Name),
+		Region:         region,
+	}
+
+	if cluster.Version != nil {
+		var version string = "v" + *cluster.Version
+		spec.Version = &version
+	}
+
+	if cluster.RoleArn != nil {
+		var name, path string
+		if name, path, err = parseRoleArn(*cluster.RoleArn); err != nil {
+			return nil, errors.Wrap(err, "invalid cluster role")
+		}
+		spec.RoleName = &name
+
+		if path != "/" {
+			annotations[rolePathAnnotation] = path
+		}
+	}
+
+	if cluster.Endpoint != nil {
+		spec.ControlPlaneEndpoint = capiinfra.APIEndpoint{
+			Host: strings.TrimPrefix(*cluster.Endpoint, "https://"),
+			Port: 443,
+		}
+	}
+
+	if vpc := cluster.ResourcesVpcConfig; vpc != nil {
+		spec.EndpointAccess = ekscontrolplanev2.EndpointAccess{
+			Public:  aws.Bool(vpc.EndpointPublicAccess),
+			Private: aws.Bool(vpc.EndpointPrivateAccess),
+		}
+		for i := range vpc.PublicAccessCidrs {
+			spec.EndpointAccess.PublicCIDRs = append(spec.EndpointAccess.PublicCIDRs, &vpc.PublicAccessCidrs[i])
+		}
+
+		spec.NetworkSpec.VPC.ID = aws.ToString(vpc.VpcId)
+		for _, subnet := range vpc.SubnetIds {
+			spec.NetworkSpec.Subnets = append(spec.NetworkSpec.Subnets, infrav2.SubnetSpec{
+				ID: subnet,
+			})
+		}
+
+		if len(vpc.SecurityGroupIds) > 0 {
+			annotations[securityGroupsAnnotation] = strings.Join(vpc.SecurityGroupIds, ",")
+		}
+	}
+
+	if cluster.KubernetesNetworkConfig != nil && cluster.KubernetesNetworkConfig.IpFamily == types.IpFamilyIpv6 {
+		spec.NetworkSpec.VPC.IPv6 = &infrav2.IPv6{}
+	}
+
+	if cluster.Logging != nil {
+		var logging *ekscontrolplanev2.ControlPlaneLoggingSpec = &ekscontrolplanev2.ControlPlaneLoggingSpec{}
+		for _, setup := range cluster.Logging.ClusterLogging {
+			if !aws.ToBool(setup.Enabled) {
+				continue
+			}
+
+			for _, t := range setup.Types {
+				if field, ok := controlPlaneLogTypes[t]; ok {
+					*field(logging) = true
+				}
+			}
+		}
+		spec.Logging = logging
+	}
+
+	// EKS allows a single encryption config per cluster
+	if len(cluster.EncryptionConfig) > 0 {
+		var encryption types.EncryptionConfig = cluster.EncryptionConfig[0]
+		spec.EncryptionConfig = &ekscontrolplanev2.EncryptionConfig{}
+		if encryption.Provider != nil {
+			spec.EncryptionConfig.Provider = encryption.Provider.KeyArn
+		}
+		for i := range encryption.Resources {
+			spec.EncryptionConfig.Resources = append(spec.EncryptionConfig.Resources, &encryption.Resources[i])
+		}
+	}
+
+	var tags map[string]string = make(map[string]string)
+	filter.merge(tags, cluster.Tags)
+	if len(tags) > 0 {
+		spec.AdditionalTags = infrav2.Tags(tags)
+	}
+	return spec, nil
+}
+
+// getIdentityProviderConfig reads the OIDC identity provider associated with
+// the cluster. CAPA holds a single identity provider so any others are
+// reported as warnings.
+func getIdentityProviderConfig(cluster string, client AwsEksApi, filter *tagFilter) (config *ekscontrolplanev2.OIDCIdentityProviderConfig, warnings []error) {
+	var (
+		input *eks.ListIdentityProviderConfigsInput = &eks.ListIdentityProviderConfigsInput{
+			ClusterName: &cluster,
+		}
+		names []string
+	)
+
+	for {
+		res, err := ListIdentityProviderConfigs(context.TODO(), client, input)
+		if err != nil {
+			return nil, append(warnings, errors.Wrap(err, "cannot list identity provider configs"))
+		}
+
+		for _, provider := range res.IdentityProviderConfigs {
+			if aws.ToString(provider.Type) == identityProviderType {
+				names = append(names, aws.ToString(provider.Name))
+			}
+		}
+
+		if res.NextToken == nil {
+			break
+		}
+		input.NextToken = res.NextToken
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	if len(names) > 1 {
+		warnings = append(warnings, fmt.Errorf("only identity provider %q is imported, CAPA cannot hold %s", names[0], strings.Join(names[1:], ", ")))
+	}
+
+	res, err := DescribeIdentityProviderConfig(context.TODO(), client, &eks.DescribeIdentityProviderConfigInput{
+		ClusterName: &cluster,
+		IdentityProviderConfig: &types.IdentityProviderConfig{
+			Name: aws.String(names[0]),
+			Type: aws.String(identityProviderType),
+		},
+	})
+	if err != nil {
+		return nil, append(warnings, errors.Wrapf(err, "cannot describe identity provider %q", names[0]))
+	}
+
+	if res.IdentityProviderConfig == nil || res.IdentityProviderConfig.Oidc == nil {
+		return nil, warnings
+	}
+
+	var oidc *types.OidcIdentityProviderConfig = res.IdentityProviderConfig.Oidc
+	config = &ekscontrolplanev2.OIDCIdentityProviderConfig{
+		ClientID:                   aws.ToString(oidc.ClientId),
+		GroupsClaim:                oidc.GroupsClaim,
+		GroupsPrefix:               oidc.GroupsPrefix,
+		IdentityProviderConfigName: aws.ToString(oidc.IdentityProviderConfigName),
+		IssuerURL:                  aws.ToString(oidc.IssuerUrl),
+		RequiredClaims:             oidc.RequiredClaims,
+		UsernameClaim:              oidc.UsernameClaim,
+		UsernamePrefix:             oidc.UsernamePrefix,
+	}
+
+	var tags map[string]string = make(map[string]string)
+	filter.merge(tags, oidc.Tags)
+	if len(tags) > 0 {
+		config.Tags = infrav2.Tags(tags)
+	}
+	return config, warnings
+}
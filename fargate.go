@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+// importFargateProfiles adds an AWSFargateProfile for each fargate profile
+// attached to the cluster
+//
+// Fargate profiles are imported alongside nodegroups so failing to read them
+// is reported as a warning rather than failing the function.
+func (f *Function) importFargateProfiles(ac *XrConfig, client AwsEksApi, filter *tagFilter, selection *nodegroupFilter) {
+	var (
+		profiles []string
+		err      error
+	)
+
+	if profiles, err = getFargateProfiles(*ac.cluster, client); err != nil {
+		ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot list fargate profiles for cluster %q", *ac.cluster))
+		return
+	}
+
+	for _, profile := range profiles {
+		var res *eks.DescribeFargateProfileOutput
+		if res, err = DescribeFargateProfile(context.TODO(), client, &eks.DescribeFargateProfileInput{
+			ClusterName:        ac.cluster,
+			FargateProfileName: aws.String(profile),
+		}); err != nil {
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot describe fargate profile %q", profile))
+			continue
+		}
+
+		if !selection.allowed(fargateProfileTarget(res.FargateProfile)) {
+			f.log.Debug("AWSAPI", "skipping fargate profile excluded by the input", profile, "cluster", *ac.cluster)
+			continue
+		}
+
+		var (
+			spec        *expinfrav2.FargateProfileSpec
+			annotations map[string]string = newAnnotations(ac, "AWSFargateProfile")
+		)
+
+		if spec, err = fargateProfileToCapiObject(*ac.cluster, res.FargateProfile, filter, annotations); err != nil {
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "fargate profile %q cannot be imported", profile))
+			continue
+		}
+
+		var name string = objectName(ac, "awsfargateprofile", profile)
+		f.log.Info("AWSAPI", "Creating fargate profile", name)
+
+		var fargate expinfrav2.AWSFargateProfile = expinfrav2.AWSFargateProfile{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "AWSFargateProfile",
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   *ac.namespace,
+				Labels:      newLabels(ac, "AWSFargateProfile", ""),
+				Annotations: annotations,
+			},
+			Spec: *spec,
+			Status: expinfrav2.FargateProfileStatus{
+				Ready: res.FargateProfile.Status == types.FargateProfileStatusActive,
+			},
+		}
+
+		if err = f.addDesired(ac, name, fargate, provenance{
+			arn:  aws.ToString(res.FargateProfile.FargateProfileArn),
+			hash: sourceHash(fargateProfileSource(res.FargateProfile)),
+		}); err != nil {
+			continue
+		}
+	}
+}
+
+// getFargateProfiles lists the names of all fargate profiles on the cluster
+func getFargateProfiles(cluster string, client AwsEksApi) (profiles []string, err error) {
+	var input *eks.ListFargateProfilesInput = &eks.ListFargateProfilesInput{
+		ClusterName: &cluster,
+	}
+
+	for {
+		var res *eks.ListFargateProfilesOutput
+		if res, err = ListFargateProfiles(context.TODO(), client, input); err != nil {
+			return nil, err
+		}
+
+		profiles = append(profiles, res.FargateProfileNames...)
+		if res.NextToken == nil {
+			return profiles, nil
+		}
+		input.NextToken = res.NextToken
+	}
+}
+
+// fargateProfileToCapiObject maps an EKS fargate profile onto the CAPA spec.
+// The IAM path of the pod execution role is added to annotations.
+func fargateProfileToCapiObject(cluster string, profile *types.FargateProfile, filter *tagFilter, annotations map[string]string) (spec *expinfrav2.FargateProfileSpec, err error) {
+	spec = &expinfrav2.FargateProfileSpec{
+		ClusterName: cluster,
+		ProfileName: aws.ToString(profile.FargateProfileName),
+		SubnetIDs:   profile.Subnets,
+	}
+
+	if profile.PodExecutionRoleArn != nil {
+		var path string
+		if spec.RoleName, path, err = parseRoleArn(*profile.PodExecutionRoleArn); err != nil {
+			return nil, errors.Wrap(err, "invalid pod execution role")
+		}
+
+		if path != "/" {
+			annotations[rolePathAnnotation] = path
+		}
+	}
+
+	for _, selector := range profile.Selectors {
+		spec.Selectors = append(spec.Selectors, expinfrav2.FargateSelector{
+			Labels:    selector.Labels,
+			Namespace: aws.ToString(selector.Namespace),
+		})
+	}
+
+	var tags map[string]string = make(map[string]string)
+	filter.merge(tags, profile.Tags)
+	if len(tags) > 0 {
+		spec.AdditionalTags = infrav2.Tags(tags)
+	}
+	return spec, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	fnv1beta1 "github.com/crossplane/function-sdk-go/proto/v1beta1"
+	"github.com/crossplane/function-sdk-go/request"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+// environmentKey is the context key Crossplane stores the environment
+// config under
+const environmentKey = "apiextensions.crossplane.io/environment"
+
+// The paths values are read from on the XR when no path is given in the input
+const (
+	defaultClusterNamePath              = "spec.clusterName"
+	defaultNamespacePath                = "spec.claimRef.namespace"
+	defaultRegionPath                   = "spec.regionOrLocation"
+	defaultCloudProviderConfigRefPath   = "spec.cloudProviderConfigRef"
+	defaultClusterProviderConfigRefPath = "spec.clusterProviderConfigRef"
+	defaultObjectDeletionPolicyPath     = "spec.objectDeletionPolicy"
+	defaultAdditionalLabelsPath         = "spec.kubernetesAdditionalLabels"
+	defaultProviderPath                 = "spec.compositionSelector.matchLabels.provider"
+)
+
+// The paths values are read from on the observed cluster resource when
+// `useObservedCluster` is set
+const (
+	externalNamePath   = "metadata.annotations[crossplane.io/external-name]"
+	observedNamePath   = "metadata.name"
+	observedRegionPath = "spec.forProvider.region"
+)
+
+// providerGroups maps the API groups of managed cluster resources onto the
+// cloud they belong to
+var providerGroups = map[string]string{
+	"eks.aws.upbound.io":                "aws",
+	"containerservice.azure.upbound.io": "azure",
+	"container.gcp.upbound.io":          "gcp",
+}
+
+// fieldSources holds the objects values can be read from
+type fieldSources struct {
+	composite, cluster, environment map[string]any
+}
+
+// newFieldSources collects the XR, the observed cluster resource and the
+// environment config of the request
+func newFieldSources(req *fnv1beta1.RunFunctionRequest, cluster map[string]any) (sources *fieldSources, err error) {
+	sources = &fieldSources{
+		cluster: cluster,
+	}
+
+	if oxr := req.GetObserved().GetComposite().GetResource(); oxr != nil {
+		sources.composite = oxr.AsMap()
+	}
+
+	if env, ok := request.GetContextKey(req, environmentKey); ok {
+		var isMap bool
+		if sources.environment, isMap = env.AsInterface().(map[string]any); !isMap {
+			return nil, fmt.Errorf("environment config in context key %q is not an object", environmentKey)
+		}
+	}
+	return
+}
+
+// fieldRef returns the reference given in the input, or a reference to the
+// default path on the XR
+func fieldRef(ref *v1beta1.FieldRef, path string) v1beta1.FieldRef {
+	if ref == nil || ref.Path == "" {
+		return v1beta1.FieldRef{Source: v1beta1.FieldSourceComposite, Path: path}
+	}
+	return *ref
+}
+
+// paved returns the source object the reference points to
+func (s *fieldSources) paved(ref v1beta1.FieldRef) (*fieldpath.Paved, error) {
+	var object map[string]any
+	switch ref.Source {
+	case v1beta1.FieldSourceComposite, "":
+		object = s.composite
+	case v1beta1.FieldSourceObservedCluster:
+		object = s.cluster
+	case v1beta1.FieldSourceEnvironment:
+		object = s.environment
+	default:
+		return nil, fmt.Errorf("unknown field source %q", ref.Source)
+	}
+
+	if object == nil {
+		object = make(map[string]any)
+	}
+	return fieldpath.Pave(object), nil
+}
+
+// getString reads a string value. Values which are not set are returned as
+// an empty string.
+func (s *fieldSources) getString(ref v1beta1.FieldRef) (value string, err error) {
+	var p *fieldpath.Paved
+	if p, err = s.paved(ref); err != nil {
+		return
+	}
+
+	if value, err = p.GetString(ref.Path); err != nil {
+		if fieldpath.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "cannot read %s path %q", sourceName(ref), ref.Path)
+	}
+	return
+}
+
+// getStringMap reads a map of strings. Values which are not set are returned
+// as an empty map.
+func (s *fieldSources) getStringMap(ref v1beta1.FieldRef) (value map[string]string, err error) {
+	var p *fieldpath.Paved
+	if p, err = s.paved(ref); err != nil {
+		return
+	}
+
+	if value, err = p.GetStringObject(ref.Path); err != nil {
+		if fieldpath.IsNotFound(err) {
+			return map[string]string{}, nil
+		}
+		return nil, errors.Wrapf(err, "cannot read %s path %q", sourceName(ref), ref.Path)
+	}
+	return
+}
+
+func sourceName(ref v1beta1.FieldRef) v1beta1.FieldSource {
+	if ref.Source == "" {
+		return v1beta1.FieldSourceComposite
+	}
+	return ref.Source
+}
+
+// resolveNamespace picks the namespace generated objects are created in. The
+// namespace set in the input comes first, then the namespace of the claim and
+// then the default namespace of the function.
+func resolveNamespace(target, claim, fallback string) (namespace string, err error) {
+	for _, namespace = range []string{target, claim, fallback} {
+		if namespace != "" {
+			return namespace, nil
+		}
+	}
+	return "", errors.New("cannot determine the namespace of generated objects: " +
+		"set targetNamespace in the input, create the XR through a claim or start the function with --default-namespace")
+}
+
+// resolveProvider picks the cloud the cluster runs on. The provider set in
+// the input comes first, then the provider read from the XR and then the
+// cloud the API group of the observed cluster resource belongs to.
+//
+// Compositions selected by name or revision carry no provider label, which
+// the API group fallback covers.
+func resolveProvider(override, selected string, cluster map[string]any) (provider string, err error) {
+	for _, provider = range []string{override, selected} {
+		if provider != "" {
+			return strings.ToLower(provider), nil
+		}
+	}
+
+	var apiVersion string
+	if apiVersion, _ = cluster["apiVersion"].(string); apiVersion == "" {
+		return "", errors.New("cannot determine the provider: the observed cluster resource has no apiVersion")
+	}
+
+	var gv schema.GroupVersion
+	if gv, err = schema.ParseGroupVersion(apiVersion); err != nil {
+		return "", errors.Wrap(err, "cannot determine the provider")
+	}
+
+	var ok bool
+	if provider, ok = providerGroups[gv.Group]; !ok {
+		return "", fmt.Errorf("cannot determine the provider: API group %q of the observed cluster resource is not known, set provider in the input", gv.Group)
+	}
+	return
+}
+
+// resolveFields reads every value the function needs from the paths given in
+// the input
+func resolveFields(ac *XrConfig, sources *fieldSources, paths *v1beta1.FieldPaths) (provider string, err error) {
+	if paths == nil {
+		paths = &v1beta1.FieldPaths{}
+	}
+
+	var clusterRef, regionRef v1beta1.FieldRef = fieldRef(paths.ClusterName, defaultClusterNamePath),
+		fieldRef(paths.Region, defaultRegionPath)
+
+	// The cluster MR already knows the name and region of the EKS cluster, so
+	// reading them from it avoids configuring them twice
+	var observed bool = ac.input != nil && ac.input.UseObservedCluster
+	if observed && paths.ClusterName == nil {
+		clusterRef = v1beta1.FieldRef{Source: v1beta1.FieldSourceObservedCluster, Path: externalNamePath}
+	}
+
+	if observed && paths.Region == nil {
+		regionRef = v1beta1.FieldRef{Source: v1beta1.FieldSourceObservedCluster, Path: observedRegionPath}
+	}
+
+	var (
+		cluster, namespace, region, providerConfigRef string
+		values                                        = []struct {
+			ref  v1beta1.FieldRef
+			into *string
+		}{
+			{clusterRef, &cluster},
+			{fieldRef(paths.Namespace, defaultNamespacePath), &namespace},
+			{regionRef, &region},
+			{fieldRef(paths.CloudProviderConfigRef, defaultCloudProviderConfigRefPath), &providerConfigRef},
+			{fieldRef(paths.ClusterProviderConfigRef, defaultClusterProviderConfigRefPath), &ac.clusterProviderConfigRef},
+			{fieldRef(paths.ObjectDeletionPolicy, defaultObjectDeletionPolicyPath), &ac.deletionPolicy},
+			{fieldRef(paths.Provider, defaultProviderPath), &provider},
+		}
+	)
+
+	for _, v := range values {
+		if *v.into, err = sources.getString(v.ref); err != nil {
+			return
+		}
+	}
+
+	// The external name is only set once the provider has seen the cluster,
+	// until then it matches the name of the MR
+	if observed && paths.ClusterName == nil && cluster == "" {
+		if cluster, err = sources.getString(v1beta1.FieldRef{Source: v1beta1.FieldSourceObservedCluster, Path: observedNamePath}); err != nil {
+			return
+		}
+	}
+
+	// Every object is named after the cluster and every AWS call is made for
+	// it, so there is nothing to import without one
+	if cluster == "" {
+		return "", fmt.Errorf("cluster name read from %s path %q is empty", sourceName(clusterRef), clusterRef.Path)
+	}
+
+	if ac.additionalLabels, err = sources.getStringMap(fieldRef(paths.AdditionalLabels, defaultAdditionalLabelsPath)); err != nil {
+		return
+	}
+
+	ac.cluster, ac.namespace, ac.region, ac.providerConfigRef = &cluster, &namespace, &region, &providerConfigRef
+	return
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"testing"
+
+	fnv1beta1 "github.com/crossplane/function-sdk-go/proto/v1beta1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+func TestResolveFields(t *testing.T) {
+	type want struct {
+		cluster, namespace, region, providerConfigRef string
+		clusterProviderConfigRef, deletionPolicy      string
+		provider                                      string
+		additionalLabels                              map[string]string
+	}
+
+	var (
+		xr string = `{"apiVersion":"example.com/v1","kind":"Cluster","spec":{
+			"clusterName":"test","claimRef":{"namespace":"default"},
+			"regionOrLocation":"eu-central-1","cloudProviderConfigRef":"aws",
+			"clusterProviderConfigRef":"kubernetes","objectDeletionPolicy":"Delete",
+			"kubernetesAdditionalLabels":{"foo":"bar"},
+			"compositionSelector":{"matchLabels":{"provider":"aws"}},
+			"parameters":{"name":"other","labels":["not","a","map"]}}}`
+		cluster string = `{"apiVersion":"eks.aws.upbound.io/v1beta1","kind":"Cluster",
+			"metadata":{"name":"test-mr","annotations":{"crossplane.io/external-name":"eks-test"}},
+			"spec":{"forProvider":{"region":"eu-west-1"}}}`
+		unnamed string = `{"apiVersion":"eks.aws.upbound.io/v1beta1","kind":"Cluster",
+			"metadata":{"name":"test-mr"},"spec":{"forProvider":{"region":"eu-west-1"}}}`
+		environment string = `{"aws":{"providerConfig":"shared"}}`
+	)
+
+	cases := map[string]struct {
+		paths    *v1beta1.FieldPaths
+		observed bool
+		cluster  string
+		want     want
+		wantErr  bool
+	}{
+		"default paths": {
+			want: want{
+				cluster:                  "test",
+				namespace:                "default",
+				region:                   "eu-central-1",
+				providerConfigRef:        "aws",
+				clusterProviderConfigRef: "kubernetes",
+				deletionPolicy:           "Delete",
+				provider:                 "aws",
+				additionalLabels:         map[string]string{"foo": "bar"},
+			},
+		},
+		"paths on every source": {
+			paths: &v1beta1.FieldPaths{
+				ClusterName:            &v1beta1.FieldRef{Path: "spec.parameters.name"},
+				Region:                 &v1beta1.FieldRef{Source: v1beta1.FieldSourceObservedCluster, Path: "spec.forProvider.region"},
+				CloudProviderConfigRef: &v1beta1.FieldRef{Source: v1beta1.FieldSourceEnvironment, Path: "aws.providerConfig"},
+				Namespace:              &v1beta1.FieldRef{Path: "spec.missing"},
+			},
+			want: want{
+				cluster:                  "other",
+				region:                   "eu-west-1",
+				providerConfigRef:        "shared",
+				clusterProviderConfigRef: "kubernetes",
+				deletionPolicy:           "Delete",
+				provider:                 "aws",
+				additionalLabels:         map[string]string{"foo": "bar"},
+			},
+		},
+		"cluster name and region from the observed cluster": {
+			observed: true,
+			want: want{
+				cluster:                  "eks-test",
+				namespace:                "default",
+				region:                   "eu-west-1",
+				providerConfigRef:        "aws",
+				clusterProviderConfigRef: "kubernetes",
+				deletionPolicy:           "Delete",
+				provider:                 "aws",
+				additionalLabels:         map[string]string{"foo": "bar"},
+			},
+		},
+		"observed cluster without an external name": {
+			observed: true,
+			cluster:  unnamed,
+			paths: &v1beta1.FieldPaths{
+				Region: &v1beta1.FieldRef{Path: "spec.regionOrLocation"},
+			},
+			want: want{
+				cluster:                  "test-mr",
+				namespace:                "default",
+				region:                   "eu-central-1",
+				providerConfigRef:        "aws",
+				clusterProviderConfigRef: "kubernetes",
+				deletionPolicy:           "Delete",
+				provider:                 "aws",
+				additionalLabels:         map[string]string{"foo": "bar"},
+			},
+		},
+		"empty cluster name": {
+			paths: &v1beta1.FieldPaths{
+				ClusterName: &v1beta1.FieldRef{Path: "spec.missing"},
+			},
+			wantErr: true,
+		},
+		"value of the wrong type": {
+			paths: &v1beta1.FieldPaths{
+				AdditionalLabels: &v1beta1.FieldRef{Path: "spec.parameters.labels"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var req *fnv1beta1.RunFunctionRequest = &fnv1beta1.RunFunctionRequest{
+				Observed: &fnv1beta1.State{
+					Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(xr)},
+				},
+				Context: resource.MustStructJSON(`{"` + environmentKey + `":` + environment + `}`),
+			}
+
+			if tc.cluster == "" {
+				tc.cluster = cluster
+			}
+
+			sources, err := newFieldSources(req, resource.MustStructJSON(tc.cluster).AsMap())
+			if err != nil {
+				t.Fatalf("newFieldSources(...): unexpected error: %v", err)
+			}
+
+			var ac XrConfig = XrConfig{
+				input: &v1beta1.Spec{UseObservedCluster: tc.observed},
+			}
+			provider, err := resolveFields(&ac, sources, tc.paths)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("resolveFields(...): unexpected error state: %v", err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			var got want = want{
+				cluster:                  *ac.cluster,
+				namespace:                *ac.namespace,
+				region:                   *ac.region,
+				providerConfigRef:        *ac.providerConfigRef,
+				clusterProviderConfigRef: ac.clusterProviderConfigRef,
+				deletionPolicy:           ac.deletionPolicy,
+				provider:                 provider,
+				additionalLabels:         ac.additionalLabels,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("resolveFields(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveNamespace(t *testing.T) {
+	cases := map[string]struct {
+		target, claim, fallback string
+		want                    string
+		wantErr                 bool
+	}{
+		"input takes precedence":         {target: "input", claim: "claim", fallback: "flag", want: "input"},
+		"claim before the default":       {claim: "claim", fallback: "flag", want: "claim"},
+		"default for XRs without claims": {fallback: "flag", want: "flag"},
+		"no namespace":                   {wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveNamespace(tc.target, tc.claim, tc.fallback)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("resolveNamespace(...): unexpected error state: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("resolveNamespace(...): want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveProvider(t *testing.T) {
+	cases := map[string]struct {
+		override, selected, apiVersion string
+		want                           string
+		wantErr                        bool
+	}{
+		"input takes precedence":      {override: "gcp", selected: "aws", apiVersion: "eks.aws.upbound.io/v1beta1", want: "gcp"},
+		"selector label before group": {selected: "AWS", apiVersion: "container.gcp.upbound.io/v1beta1", want: "aws"},
+		"aws from the api group":      {apiVersion: "eks.aws.upbound.io/v1beta1", want: "aws"},
+		"azure from the api group":    {apiVersion: "containerservice.azure.upbound.io/v1beta1", want: "azure"},
+		"gcp from the api group":      {apiVersion: "container.gcp.upbound.io/v1beta2", want: "gcp"},
+		"unknown api group":           {apiVersion: "eks.aws.crossplane.io/v1beta1", wantErr: true},
+		"no api version":              {wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var cluster map[string]any = map[string]any{}
+			if tc.apiVersion != "" {
+				cluster["apiVersion"] = tc.apiVersion
+			}
+
+			got, err := resolveProvider(tc.override, tc.selected, cluster)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("resolveProvider(...): unexpected error state: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("resolveProvider(...): want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"strings"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	fnv1beta1 "github.com/crossplane/function-sdk-go/proto/v1beta1"
@@ -34,30 +33,68 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 		return rsp, nil
 	}
 
-	if _, ok := ac.composed.ObservedComposed[input.Spec.ClusterRef]; !ok {
+	observed, ok := ac.composed.ObservedComposed[input.Spec.ClusterRef]
+	if !ok {
 		return rsp, nil
 	}
+	ac.observedCluster = observed.Resource
 
-	ac.cluster = &ac.composite.Spec.ClusterName
-	ac.namespace = &ac.composite.Spec.ClaimRef.Namespace
-	ac.region = &ac.composite.Spec.Region
-	ac.providerConfigRef = &ac.composite.Spec.CloudProviderConfigRef
+	ac.input = input.Spec
 
+	var (
+		sources  *fieldSources
+		provider string
+	)
+	if sources, err = newFieldSources(req, ac.observedCluster.Object); err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+
+	if provider, err = resolveFields(&ac, sources, input.Spec.FieldPaths); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot resolve field paths"))
+		return rsp, nil
+	}
+
+	// A cluster of an unknown cloud is left alone rather than failing the
+	// composition it is part of
+	if provider, err = resolveProvider(input.Spec.Provider, provider, sources.cluster); err != nil {
+		response.Warning(rsp, err)
+		return rsp, nil
+	}
+
+	// Labels and annotations of the XR are never written to directly. Each
+	// generated object is given its own copy through newLabels and
+	// newAnnotations.
 	ac.labels = ac.composite.Metadata.Labels
-	if ac.labels == nil {
-		ac.labels = make(map[string]string)
+	ac.annotations = ac.composite.Metadata.Annotations
+	if ac.policy, err = newMetadataPolicy(input.Spec.Propagation); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "invalid propagation policy"))
+		return rsp, nil
 	}
-	for k, v := range ac.composite.Spec.KubernetesAdditionalLabels {
-		ac.labels[k] = v
+
+	if ac.nameTemplate, err = newNameTemplate(input.Spec.NameTemplate); err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
 	}
-	ac.labels["cluster.x-k8s.io/cluster-name"] = *ac.cluster
-	ac.labels["giantswarm.io/cluster"] = *ac.cluster
 
-	var provider string = ac.composite.Spec.CompositionSelector.MatchLabels.Provider
+	var warnings []error
+	ac.patches, warnings = newPatches(input.Spec.Patches)
+	ac.warnings = append(ac.warnings, warnings...)
+
 	{
-		switch strings.ToLower(provider) {
+		switch provider {
 		case "aws":
 			f.log.Info("discovered aws provider", composedName, req.GetMeta().GetTag())
+
+			// Objects without a namespace cannot be created, so nothing is
+			// generated rather than emitting objects which are bound to fail
+			var namespace string
+			if namespace, err = resolveNamespace(input.Spec.TargetNamespace, *ac.namespace, f.defaultNamespace); err != nil {
+				response.Fatal(rsp, err)
+				return rsp, nil
+			}
+			ac.namespace = &namespace
+
 			if err = f.CreateAWSNodegroupSpec(&ac); err != nil {
 				response.Fatal(rsp, errors.Wrapf(err, "cannot create composed resources from %T", req))
 				return rsp, nil
@@ -69,6 +106,10 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 		}
 	}
 
+	for _, warning := range ac.warnings {
+		response.Warning(rsp, warning)
+	}
+
 	if err = ac.composed.ToResponse(rsp); err != nil {
 		response.Fatal(rsp, errors.Wrapf(err, "cannot convert composition to response %T", rsp))
 		return
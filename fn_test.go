@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"testing"
 	"time"
@@ -13,6 +14,8 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/protobuf/testing/protocmp"
@@ -44,6 +47,26 @@ var (
 	},"kubernetesAdditionalLabels": {"foo": "bar"},
 	"compositionSelector": {"matchLabels": {"provider": "aws"}}}}`
 
+	xrUnclaimed = `{"apiVersion": "example.org/v1","kind": "XR", "spec": {
+	"clusterName": "example","clusterProviderConfigRef": "thingy",
+	"regionOrLocation": "placey", "deletionPolicy": "Delete",
+	"objectDeletionPolicy": "Delete",
+	"compositionSelector": {"matchLabels": {"provider": "aws"}}}}`
+
+	xrKarpenterTest = `{"apiVersion": "example.org/v1","kind": "XR", "spec": {
+	"clusterName": "test","clusterProviderConfigRef": "thingy",
+	"regionOrLocation": "placey", "deletionPolicy": "Delete",
+	"objectDeletionPolicy": "Delete", "claimRef":{"namespace":"default"},
+	"labels": {"test": "label","anothertest": "label"
+	},"kubernetesAdditionalLabels": {"foo": "bar"},
+	"compositionSelector": {"matchLabels": {"provider": "aws"}}},
+	"status": {"karpenter": {"instances": 4, "nodePools": [
+	{"name": "batch", "instances": 1, "instanceTypes": {"m5.large": 1},
+	"zones": {"eu-central-1c": 1}},
+	{"name": "default", "instances": 3,
+	"instanceTypes": {"c5.xlarge": 1, "m5.large": 2},
+	"zones": {"eu-central-1a": 2, "eu-central-1b": 1}}]}}}`
+
 	clusterExample = `{"apiVersion": "eks.aws.upbound.io/v1beta1","kind":"Cluster",
 	"metadata": {"annotations": {"crossplane.io/external-name": "example",
 	"crossplane.io/composition-resource-name": "eks-cluster"}, "labels": {
@@ -59,100 +82,504 @@ var (
 	"labels":{"crossplane.io/claim-name":"test"}},"managementPolicies":["Observe"],
 	"spec":{"forProvider":{"region":"eu-central-1"},"providerConfigRef":{
 	"name":"example"},"writeConnectionSecretToRef":{"namespace":"example"}},
-	"status":{"atProvider":{"vpcConfig":[{"vpcId":"vpc-12345678",
+	"status":{"atProvider":{"version":"1.29","vpcConfig":[{"vpcId":"vpc-12345678",
 	"subnetIds":["subnet-123456"]}]}}}`
 
-	nodepoolExample = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1",
-	"kind":"Object","metadata":{"labels":{"foo":"bar",
-	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345",
-	"cluster.x-k8s.io/cluster-name":"example"},
-	"name":"example-awsmanagedmachinepool-ng-12345"},"spec":{
-	"deletionPolicy":"Delete","forProvider":{"manifest":{
-	"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
-	"kind":"AWSManagedMachinePool","metadata":{"labels":{"foo":"bar",
-	"cluster.x-k8s.io/cluster-name":"example","giantswarm.io/cluster":"example",
+	nodepoolExample = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"example","foo":"bar",
+	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-awsmanagedmachinepool-ng-12345"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedMachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/ami-family":"AmazonLinux2",
+	"describenodegroups.fn.giantswarm.io/ami-id":"ami-0ab553a58389ae35a",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-123456",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"856152d8693e4da17edfab4120fc20982066b482ec805db39cc3b75467a95af9"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"example",
+	"foo":"bar","giantswarm.io/cluster":"example",
 	"giantswarm.io/machine-pool":"ng-12345"},
-	"name":"example-awsmanagedmachinepool-ng-12345","namespace":"default",
-	"creationTimestamp":null},"spec":{"amiType":"AL2_x86_64","availabilityZones":[
-	"eu-central-1a","eu-central-1c","eu-central-1b"],"awsLaunchTemplate":{
-	"additionalSecurityGroups":[{"id":"sg-11111111111111111"},{
-	"id":"sg-22222222222222222"}],"ami":{"id":"ami-0ab553a58389ae35a"},
+	"name":"example-awsmanagedmachinepool-ng-12345","namespace":"default"},
+	"spec":{"amiType":"AL2_x86_64","availabilityZones":["eu-central-1a",
+	"eu-central-1c","eu-central-1b"],
+	"awsLaunchTemplate":{"additionalSecurityGroups":[{"id":"sg-11111111111111111"},
+	{"id":"sg-22222222222222222"}],"ami":{"eksLookupType":"AmazonLinux"},
 	"instanceType":"m5.large","name":"eksctl-example-nodegroup-ng-1",
-	"rootVolume":{"deviceName":"/dev/xvda","iops":3000,"size":80,
-	"throughput":125,"type":"gp3"},"spotMarketOptions":{"maxPrice":"expensive"},
+	"rootVolume":{"deviceName":"/dev/xvda","iops":3000,"size":80,"throughput":125,
+	"type":"gp3"},"spotMarketOptions":{"maxPrice":"expensive"},
 	"sshKeyName":"test-key","versionNumber":1},"capacityType":"onDemand",
-	"eksNodegroupName":"ng-12345","providerIDList":["aws:///eu-central-1c/i-1111111111111111",
-	"aws:///eu-central-1a/i-2222222222222222","aws:///eu-central-1b/i-3333333333333333"],
-	"roleName":"eksctl-example-nodegroup-NodeInstanceRole-123456789123","scaling":{"maxSize":3,
-	"minSize":1},"subnetIDs":["subnet-1111111111111111","subnet-2222222222222222",
-	"subnet-3333333333333333"],"updateConfig":{"maxUnavailable":1}},
-	"status":{"launchTemplateID":"lt-123456","launchTemplateVersion":"2","ready":true,
-	"replicas":3}}},"providerConfigRef":{"name":"thingy"},"writeConnectionSecretToRef":{
-	"name":"example-awsmanagedmachinepool-ng-12345","namespace":"default"}}}`
+	"eksNodegroupName":"ng-12345",
+	"providerIDList":["aws:///eu-central-1c/i-1111111111111111",
+	"aws:///eu-central-1a/i-2222222222222222",
+	"aws:///eu-central-1b/i-3333333333333333"],
+	"roleName":"eksctl-example-nodegroup-NodeInstanceRole-123456789123",
+	"scaling":{"maxSize":3,"minSize":1},"subnetIDs":["subnet-1111111111111111",
+	"subnet-2222222222222222","subnet-3333333333333333"],
+	"updateConfig":{"maxUnavailable":1}},"status":{"launchTemplateID":"lt-123456",
+	"launchTemplateVersion":"2","ready":true,"replicas":3}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"example-awsmanagedmachinepool-ng-12345",
+	"namespace":"default"}}}`
 
 	nodepoolTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
-	"metadata":{"labels":{"foo":"bar","giantswarm.io/cluster":"test",
-	"giantswarm.io/machine-pool":"ng-23456","cluster.x-k8s.io/cluster-name":"test"},
-	"name":"test-awsmanagedmachinepool-ng-23456"},"spec":{"deletionPolicy":"Delete",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"ng-23456"},
+	"name":"test-awsmanagedmachinepool-ng-23456"},
+	"spec":{"deletionPolicy":"Delete",
 	"forProvider":{"manifest":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
-	"kind":"AWSManagedMachinePool","metadata":{"labels":{"foo":"bar",
-	"cluster.x-k8s.io/cluster-name":"test","giantswarm.io/cluster":"test",
-	"giantswarm.io/machine-pool":"ng-23456"},"name":"test-awsmanagedmachinepool-ng-23456",
-	"namespace":"default","creationTimestamp":null},"spec":{"amiType":"AL2_x86_64",
+	"kind":"AWSManagedMachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/ami-family":"Custom",
+	"describenodegroups.fn.giantswarm.io/ami-pinned":"true",
+	"describenodegroups.fn.giantswarm.io/autoscaling-groups":"asg-23456",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-234567",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/role-path":"/nodes/",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"a808df1a22f81eaadaf4817b7b2b80d3f1f9fb9846c2e48a2dcb3fa9e1227db4",
+	"describenodegroups.fn.giantswarm.io/update-strategy":"MINIMAL",
+	"launchtemplate.describenodegroups.fn.giantswarm.io/placement":"{\"tenancy\":\"dedicated\"}",
+	"launchtemplate.describenodegroups.fn.giantswarm.io/tag-specifications":"{\"volume\":{\"backup\":\"daily\"}}"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test",
+	"giantswarm.io/machine-pool":"ng-23456"},
+	"name":"test-awsmanagedmachinepool-ng-23456","namespace":"default"},
+	"spec":{"additionalTags":{"cost-center":"1234","owner":"platform",
+	"team":"honeybadger"},"amiType":"AL2_x86_64","amiVersion":"1.25.16-20240202",
 	"availabilityZones":["eu-central-1a","eu-central-1c","eu-central-1b"],
 	"awsLaunchTemplate":{"additionalSecurityGroups":[{"id":"sg-11111111111111111"},
-	{"id":"sg-22222222222222222"}],"ami":{"id":"ami-0ab553a58389ae35a"},
-	"instanceType":"m5.large","iamInstanceProfile": "arn::123456789:/role/something",
-	"name":"eksctl-test-nodegroup-ng-1","rootVolume":{
-	"deviceName":"/dev/xvda","iops":3000,"size":80,"throughput":125,"type":"gp3"},
-	"spotMarketOptions":{"maxPrice":"expensive"},"sshKeyName":"test-key",
-	"versionNumber":1},"capacityType":"onDemand","eksNodegroupName":"ng-23456",
+	{"id":"sg-22222222222222222"},{"id":"sg-33333333333333333"}],
+	"ami":{"id":"ami-0c5bd0a7f9b3e6f2e"},
+	"iamInstanceProfile":"arn::123456789:/role/something",
+	"instanceMetadataOptions":{"httpEndpoint":"enabled",
+	"httpPutResponseHopLimit":2,"httpTokens":"required"},
+	"instanceType":"m5.large","name":"eksctl-test-nodegroup-ng-1",
+	"rootVolume":{"deviceName":"/dev/xvda","iops":3000,"size":80,"throughput":125,
+	"type":"gp3"},"spotMarketOptions":{"maxPrice":"expensive"},
+	"sshKeyName":"test-key","versionNumber":1},"capacityType":"onDemand",
+	"eksNodegroupName":"ng-23456","labels":{"role":"worker"},
 	"providerIDList":["aws:///eu-central-1c/i-1111111111111111",
-	"aws:///eu-central-1a/i-2222222222222222","aws:///eu-central-1b/i-3333333333333333"],
-	"roleName":"eksctl-test-nodegroup-NodeInstanceRole-123456789123","scaling":{
-	"maxSize":3,"minSize":1},"subnetIDs":["subnet-1111111111111111",
-	"subnet-2222222222222222","subnet-3333333333333333"],"updateConfig":{
-	"maxUnavailable":1}},"status":{"launchTemplateID":"lt-234567",
-	"launchTemplateVersion":"2","ready":true,"replicas":3}}},"providerConfigRef":{
-	"name":"thingy"},"writeConnectionSecretToRef":{
-	"name":"test-awsmanagedmachinepool-ng-23456","namespace":"default"}}}`
-
-	machinepoolExample = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1",
-	"kind":"Object","metadata":{"labels":{"foo":"bar",
-	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345",
-	"cluster.x-k8s.io/cluster-name":"example"},
+	"aws:///eu-central-1a/i-2222222222222222",
+	"aws:///eu-central-1b/i-3333333333333333"],
+	"roleAdditionalPolicies":["arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy",
+	"arn:aws:iam::123456789012:policy/nodes-extra"],
+	"roleName":"eksctl-test-nodegroup-NodeInstanceRole-123456789123",
+	"scaling":{"maxSize":3,"minSize":1},"subnetIDs":["subnet-1111111111111111",
+	"subnet-2222222222222222","subnet-3333333333333333"],
+	"taints":[{"effect":"no-schedule","key":"dedicated","value":""}],
+	"updateConfig":{"maxUnavailable":1}},"status":{"launchTemplateID":"lt-234567",
+	"launchTemplateVersion":"2","ready":true,"replicas":3}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-awsmanagedmachinepool-ng-23456",
+	"namespace":"default"}}}`
+
+	machinepoolExample = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"example","foo":"bar",
+	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345"},
 	"name":"example-machinepool-ng-12345"},"spec":{"deletionPolicy":"Delete",
 	"forProvider":{"manifest":{"apiVersion":"cluster.x-k8s.io/v1beta1",
-	"kind":"MachinePool","metadata":{"labels":{"foo":"bar",
-	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345",
-	"cluster.x-k8s.io/cluster-name":"example"},"name":"example-machinepool-ng-12345",
-	"namespace":"default","creationTimestamp":null},"spec":{"clusterName":"example",
-	"replicas":3,"selector":{},"template":{"metadata":{},"spec":{"bootstrap":{
-	"dataSecretName":""},"clusterName":"example","infrastructureRef":{
-	"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
-	"kind":"AWSManagedMachinePool","name":"example-awsmanagedmachinepool-ng-12345",
-	"namespace":"default"}}}},"status":{"availableReplicas":0,"readyReplicas":0,
+	"kind":"MachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-123456",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"856152d8693e4da17edfab4120fc20982066b482ec805db39cc3b75467a95af9"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"example",
+	"foo":"bar","giantswarm.io/cluster":"example",
+	"giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-machinepool-ng-12345","namespace":"default"},
+	"spec":{"clusterName":"example","replicas":3,"selector":{},
+	"template":{"metadata":{},"spec":{"bootstrap":{"dataSecretName":""},
+	"clusterName":"example",
+	"infrastructureRef":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedMachinePool",
+	"name":"example-awsmanagedmachinepool-ng-12345","namespace":"default"},
+	"version":"v1.25"}}},"status":{"availableReplicas":0,"readyReplicas":0,
 	"replicas":0,"unavailableReplicas":0,"updatedReplicas":0}}},
-	"providerConfigRef":{"name":"thingy"},"writeConnectionSecretToRef":{
-	"name":"example-machinepool-ng-12345","namespace":"default"}}}`
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"example-machinepool-ng-12345",
+	"namespace":"default"}}}`
 
 	machinepoolTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
-	"metadata":{"labels":{"foo":"bar","giantswarm.io/cluster":"test",
-	"giantswarm.io/machine-pool":"ng-23456","cluster.x-k8s.io/cluster-name":"test"},
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"ng-23456"},
+	"name":"test-machinepool-ng-23456"},"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"cluster.x-k8s.io/v1beta1",
+	"kind":"MachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/autoscaling-groups":"asg-23456",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-234567",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"a808df1a22f81eaadaf4817b7b2b80d3f1f9fb9846c2e48a2dcb3fa9e1227db4"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test",
+	"giantswarm.io/machine-pool":"ng-23456"},"name":"test-machinepool-ng-23456",
+	"namespace":"default"},"spec":{"clusterName":"test","replicas":3,
+	"selector":{},"template":{"metadata":{},
+	"spec":{"bootstrap":{"dataSecretName":""},"clusterName":"test",
+	"infrastructureRef":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedMachinePool","name":"test-awsmanagedmachinepool-ng-23456",
+	"namespace":"default"},"version":"v1.25.16"}}},
+	"status":{"availableReplicas":0,"readyReplicas":0,"replicas":0,
+	"unavailableReplicas":0,"updatedReplicas":0}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-machinepool-ng-23456",
+	"namespace":"default"}}}`
+
+	fargateTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test"},"name":"test-awsfargateprofile-fp-default"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSFargateProfile",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/role-path":"/fargate/",
+	"describenodegroups.fn.giantswarm.io/source-hash":"2499840ed94a0537541c240f1ec47a9e34d1e3e39feef065ba2e30e5ccaa4f06"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test"},
+	"name":"test-awsfargateprofile-fp-default","namespace":"default"},
+	"spec":{"additionalTags":{"cost-center":"5678"},"clusterName":"test",
+	"profileName":"fp-default","roleName":"test-pod-execution",
+	"selectors":[{"labels":{"k8s-app":"kube-dns"},"namespace":"kube-system"},
+	{"namespace":"serverless"}],"subnetIDs":["subnet-1111111111111111",
+	"subnet-2222222222222222"]},"status":{"ready":true}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-awsfargateprofile-fp-default",
+	"namespace":"default"}}}`
+
+	eksconfigLegacy = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"legacy-workers"},
+	"name":"test-eksconfig-legacy-workers"},"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"bootstrap.cluster.x-k8s.io/v1beta2",
+	"kind":"EKSConfig",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/autoscaling-groups":"legacy-workers",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-345678",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"4",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-hash":"321ce7b984bc0d224e23b501232ba36d49dc7787aded9b2d415b3518415de37e"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test",
+	"giantswarm.io/machine-pool":"legacy-workers"},
+	"name":"test-eksconfig-legacy-workers","namespace":"default"},
+	"spec":{"kubeletExtraArgs":{"max-pods":"29"}},"status":{}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-eksconfig-legacy-workers",
+	"namespace":"default"}}}`
+
+	awsmachinepoolLegacy = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"legacy-workers"},
+	"name":"test-awsmachinepool-legacy-workers"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSMachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/ami-family":"AmazonLinux2",
+	"describenodegroups.fn.giantswarm.io/ami-id":"ami-0ab553a58389ae35a",
+	"describenodegroups.fn.giantswarm.io/autoscaling-groups":"legacy-workers",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-345678",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"4",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-hash":"321ce7b984bc0d224e23b501232ba36d49dc7787aded9b2d415b3518415de37e"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test",
+	"giantswarm.io/machine-pool":"legacy-workers"},
+	"name":"test-awsmachinepool-legacy-workers","namespace":"default"},
+	"spec":{"additionalTags":{"owner":"platform","team":"legacy"},
+	"availabilityZones":["eu-central-1a","eu-central-1b"],
+	"awsLaunchTemplate":{"additionalSecurityGroups":[{"id":"sg-44444444444444444"}],
+	"ami":{"eksLookupType":"AmazonLinux"},
+	"iamInstanceProfile":"legacy-workers-profile","instanceType":"m5.large",
+	"name":"legacy-workers","versionNumber":4},"capacityRebalance":true,
+	"defaultCoolDown":"5m0s","maxSize":5,"minSize":1,
+	"mixedInstancesPolicy":{"instancesDistribution":{"onDemandBaseCapacity":1,
+	"onDemandPercentageAboveBaseCapacity":0,
+	"spotAllocationStrategy":"price-capacity-optimized"},
+	"overrides":[{"instanceType":"m5.large"},{"instanceType":"m5a.large"}]},
+	"providerIDList":["aws:///eu-central-1a/i-4444444444444444",
+	"aws:///eu-central-1b/i-5555555555555555"],
+	"subnets":[{"id":"subnet-1111111111111111"},
+	{"id":"subnet-2222222222222222"}]},"status":{"launchTemplateID":"lt-345678",
+	"launchTemplateVersion":"4","ready":true,"replicas":2}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-awsmachinepool-legacy-workers",
+	"namespace":"default"}}}`
+
+	machinepoolLegacy = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"legacy-workers"},
+	"name":"test-machinepool-legacy-workers"},"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"cluster.x-k8s.io/v1beta1",
+	"kind":"MachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/autoscaling-groups":"legacy-workers",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-345678",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"4",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-hash":"321ce7b984bc0d224e23b501232ba36d49dc7787aded9b2d415b3518415de37e"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test",
+	"giantswarm.io/machine-pool":"legacy-workers"},
+	"name":"test-machinepool-legacy-workers","namespace":"default"},
+	"spec":{"clusterName":"test","replicas":2,"selector":{},
+	"template":{"metadata":{},
+	"spec":{"bootstrap":{"configRef":{"apiVersion":"bootstrap.cluster.x-k8s.io/v1beta2",
+	"kind":"EKSConfig","name":"test-eksconfig-legacy-workers",
+	"namespace":"default"}},"clusterName":"test",
+	"infrastructureRef":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSMachinePool","name":"test-awsmachinepool-legacy-workers",
+	"namespace":"default"},"version":"v1.25"}}},"status":{"availableReplicas":0,
+	"readyReplicas":0,"replicas":0,"unavailableReplicas":0,"updatedReplicas":0}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-machinepool-legacy-workers",
+	"namespace":"default"}}}`
+
+	controlplaneTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test"},"name":"test-awsmanagedcontrolplane"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"controlplane.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedControlPlane",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/role-path":"/eks/",
+	"describenodegroups.fn.giantswarm.io/security-groups":"sg-55555555555555555",
+	"describenodegroups.fn.giantswarm.io/source-hash":"00913b37dfb7fb5a3b359381e034ba5ff12efd3913766e77057821512f214896"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test"},
+	"name":"test-awsmanagedcontrolplane","namespace":"default"},
+	"spec":{"additionalTags":{"owner":"platform"},
+	"addons":[{"configuration":"{\"env\":{\"ENABLE_PREFIX_DELEGATION\":\"true\"}}",
+	"conflictResolution":"none","name":"vpc-cni","version":"v1.16.0-eksbuild.1"},
+	{"conflictResolution":"none","name":"coredns","version":"v1.11.1-eksbuild.4"},
+	{"name":"kube-proxy","version":"v1.29.0-eksbuild.1"},
+	{"conflictResolution":"overwrite","name":"aws-ebs-csi-driver",
+	"serviceAccountRoleARN":"arn:aws:iam::123456789012:role/ebs-csi-controller",
+	"version":"v1.26.1-eksbuild.1"}],"associateOIDCProvider":true,
+	"bastion":{"enabled":false},
+	"controlPlaneEndpoint":{"host":"EXAMPLE.gr7.eu-central-1.eks.amazonaws.com",
+	"port":443},"eksClusterName":"test",
+	"encryptionConfig":{"provider":"arn:aws:kms:eu-central-1:123456789012:key/1234abcd",
+	"resources":["secrets"]},"endpointAccess":{"private":true,"public":true,
+	"publicCIDRs":["203.0.113.0/24"]},"kubeProxy":{},"logging":{"apiServer":true,
+	"audit":true,"authenticator":false,"controllerManager":false,
+	"scheduler":false},"network":{"subnets":[{"id":"subnet-1111111111111111",
+	"isPublic":false},{"id":"subnet-2222222222222222","isPublic":false}],
+	"vpc":{"id":"vpc-12345678"}},
+	"oidcIdentityProviderConfig":{"clientId":"kubernetes","groupsClaim":"groups",
+	"identityProviderConfigName":"dex","issuerUrl":"https://dex.example.com",
+	"usernameClaim":"email"},"region":"placey","roleName":"test-cluster",
+	"version":"v1.29","vpcCni":{}},"status":{"identityProviderStatus":{},
+	"initialized":true,"networkStatus":{"apiServerElb":{"attributes":{}}},
+	"oidcProvider":{},"ready":true}}},"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-awsmanagedcontrolplane",
+	"namespace":"default"}}}`
+
+	awsmanagedclusterTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test"},"name":"test-awsmanagedcluster"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedCluster",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-hash":"00913b37dfb7fb5a3b359381e034ba5ff12efd3913766e77057821512f214896"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test"},"name":"test-awsmanagedcluster",
+	"namespace":"default"},
+	"spec":{"controlPlaneEndpoint":{"host":"EXAMPLE.gr7.eu-central-1.eks.amazonaws.com",
+	"port":443}},"status":{"ready":true}}},"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-awsmanagedcluster",
+	"namespace":"default"}}}`
+
+	capiClusterTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test"},"name":"test"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"cluster.x-k8s.io/v1beta1",
+	"kind":"Cluster",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-hash":"00913b37dfb7fb5a3b359381e034ba5ff12efd3913766e77057821512f214896"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test"},"name":"test",
+	"namespace":"default"},
+	"spec":{"clusterNetwork":{"services":{"cidrBlocks":["172.20.0.0/16"]}},
+	"controlPlaneEndpoint":{"host":"EXAMPLE.gr7.eu-central-1.eks.amazonaws.com",
+	"port":443},
+	"controlPlaneRef":{"apiVersion":"controlplane.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedControlPlane","name":"test-awsmanagedcontrolplane",
+	"namespace":"default"},
+	"infrastructureRef":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedCluster","name":"test-awsmanagedcluster",
+	"namespace":"default"}},"status":{"controlPlaneReady":true,
+	"infrastructureReady":true}}},"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test","namespace":"default"}}}`
+
+	ec2nodeclassExample = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"example","foo":"bar",
+	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-ec2nodeclass-ng-12345"},"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"karpenter.k8s.aws/v1",
+	"kind":"EC2NodeClass",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-123456",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"856152d8693e4da17edfab4120fc20982066b482ec805db39cc3b75467a95af9"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"example",
+	"foo":"bar","giantswarm.io/cluster":"example",
+	"giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-ec2nodeclass-ng-12345"},
+	"spec":{"amiSelectorTerms":[{"alias":"al2@latest"}],
+	"blockDeviceMappings":[{"deviceName":"/dev/xvda","ebs":{"iops":3000,
+	"throughput":125,"volumeSize":"80Gi","volumeType":"gp3"},"rootVolume":true}],
+	"role":"eksctl-example-nodegroup-NodeInstanceRole-123456789123",
+	"securityGroupSelectorTerms":[{"id":"sg-11111111111111111"},
+	{"id":"sg-22222222222222222"}],
+	"subnetSelectorTerms":[{"id":"subnet-1111111111111111"},
+	{"id":"subnet-2222222222222222"},{"id":"subnet-3333333333333333"}]}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"example-ec2nodeclass-ng-12345",
+	"namespace":""}}}`
+
+	karpenterNodepoolExample = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"example","foo":"bar",
+	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-nodepool-ng-12345"},"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"karpenter.sh/v1","kind":"NodePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-123456",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"856152d8693e4da17edfab4120fc20982066b482ec805db39cc3b75467a95af9"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"example",
+	"foo":"bar","giantswarm.io/cluster":"example",
+	"giantswarm.io/machine-pool":"ng-12345"},"name":"example-nodepool-ng-12345"},
+	"spec":{"template":{"metadata":{},
+	"spec":{"nodeClassRef":{"group":"karpenter.k8s.aws","kind":"EC2NodeClass",
+	"name":"example-ec2nodeclass-ng-12345"},
+	"requirements":[{"key":"node.kubernetes.io/instance-type","operator":"In",
+	"values":["m5.large"]},{"key":"topology.kubernetes.io/zone","operator":"In",
+	"values":["eu-central-1a","eu-central-1c","eu-central-1b"]},
+	{"key":"kubernetes.io/arch","operator":"In","values":["amd64"]},
+	{"key":"karpenter.sh/capacity-type","operator":"In",
+	"values":["on-demand"]}]}}}}},"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"example-nodepool-ng-12345",
+	"namespace":""}}}`
+	machinepoolAutoscalerTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"ng-23456"},
 	"name":"test-machinepool-ng-23456"},"spec":{"deletionPolicy":"Delete",
 	"forProvider":{"manifest":{"apiVersion":"cluster.x-k8s.io/v1beta1",
-	"kind":"MachinePool","metadata":{"labels":{"foo":"bar",
-	"giantswarm.io/cluster":"test","giantswarm.io/machine-pool":"ng-23456",
-	"cluster.x-k8s.io/cluster-name":"test"},"name":"test-machinepool-ng-23456",
-	"namespace":"default","creationTimestamp":null},"spec":{"clusterName":"test",
-	"replicas":3,"selector":{},"template":{"metadata":{},"spec":{
-	"bootstrap":{"dataSecretName":""},"clusterName":"test",
+	"kind":"MachinePool",
+	"metadata":{"annotations":{"cluster.x-k8s.io/cluster-api-autoscaler-node-group-max-size":"3",
+	"cluster.x-k8s.io/cluster-api-autoscaler-node-group-min-size":"1",
+	"describenodegroups.fn.giantswarm.io/autoscaling-groups":"asg-23456",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/launch-template-id":"lt-234567",
+	"describenodegroups.fn.giantswarm.io/launch-template-version":"2",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"a808df1a22f81eaadaf4817b7b2b80d3f1f9fb9846c2e48a2dcb3fa9e1227db4"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test",
+	"giantswarm.io/machine-pool":"ng-23456"},"name":"test-machinepool-ng-23456",
+	"namespace":"default"},"spec":{"clusterName":"test","replicas":3,
+	"selector":{},"template":{"metadata":{},
+	"spec":{"bootstrap":{"dataSecretName":""},"clusterName":"test",
 	"infrastructureRef":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
 	"kind":"AWSManagedMachinePool","name":"test-awsmanagedmachinepool-ng-23456",
-	"namespace":"default"}}}},"status":{"availableReplicas":0,"readyReplicas":0,
+	"namespace":"default"},"version":"v1.25.16"}}},
+	"status":{"availableReplicas":0,"readyReplicas":0,"replicas":0,
+	"unavailableReplicas":0,"updatedReplicas":0}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-machinepool-ng-23456",
+	"namespace":"default"}}}`
+
+	configmapAutoscalerTest = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"test","foo":"bar",
+	"giantswarm.io/cluster":"test"},"name":"test-cluster-autoscaler"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"v1",
+	"data":{"nodes":"1:3:asg-23456\n"},"kind":"ConfigMap",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/autoscaling-groups":"asg-23456",
+	"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-hash":"94d6e6b5eaecb3a5d17bafda9b305ddbc7fec8facbd120cc3b8ba380f32f9146"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"test",
+	"foo":"bar","giantswarm.io/cluster":"test"},"name":"test-cluster-autoscaler",
+	"namespace":"default"}}},"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"test-cluster-autoscaler",
+	"namespace":"default"}}}`
+
+	nodepoolNoLaunchTemplate = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"example","foo":"bar",
+	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-awsmanagedmachinepool-ng-12345"},
+	"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedMachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"3e1961bcfbc5c2cafd49416cdca2de5842aff1fa5c135cc03441acdec6479048"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"example",
+	"foo":"bar","giantswarm.io/cluster":"example",
+	"giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-awsmanagedmachinepool-ng-12345","namespace":"default"},
+	"spec":{"amiType":"AL2_x86_64","availabilityZones":["eu-central-1a",
+	"eu-central-1c","eu-central-1b"],"capacityType":"onDemand",
+	"eksNodegroupName":"ng-12345","instanceType":"m5.large",
+	"providerIDList":["aws:///eu-central-1c/i-1111111111111111",
+	"aws:///eu-central-1a/i-2222222222222222",
+	"aws:///eu-central-1b/i-3333333333333333"],
+	"roleName":"eksctl-example-nodegroup-NodeInstanceRole-123456789123",
+	"scaling":{"maxSize":3,"minSize":1},"subnetIDs":["subnet-1111111111111111",
+	"subnet-2222222222222222","subnet-3333333333333333"],
+	"updateConfig":{"maxUnavailable":1}},"status":{"ready":true,"replicas":3}}},
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"example-awsmanagedmachinepool-ng-12345",
+	"namespace":"default"}}}`
+
+	machinepoolNoLaunchTemplate = `{"apiVersion":"kubernetes.crossplane.io/v1alpha1","kind":"Object",
+	"metadata":{"labels":{"cluster.x-k8s.io/cluster-name":"example","foo":"bar",
+	"giantswarm.io/cluster":"example","giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-machinepool-ng-12345"},"spec":{"deletionPolicy":"Delete",
+	"forProvider":{"manifest":{"apiVersion":"cluster.x-k8s.io/v1beta1",
+	"kind":"MachinePool",
+	"metadata":{"annotations":{"describenodegroups.fn.giantswarm.io/imported-at":"2024-03-01T12:00:00Z",
+	"describenodegroups.fn.giantswarm.io/region":"placey",
+	"describenodegroups.fn.giantswarm.io/source-arn":"arn::123456:some-role",
+	"describenodegroups.fn.giantswarm.io/source-hash":"3e1961bcfbc5c2cafd49416cdca2de5842aff1fa5c135cc03441acdec6479048"},
+	"creationTimestamp":null,"labels":{"cluster.x-k8s.io/cluster-name":"example",
+	"foo":"bar","giantswarm.io/cluster":"example",
+	"giantswarm.io/machine-pool":"ng-12345"},
+	"name":"example-machinepool-ng-12345","namespace":"default"},
+	"spec":{"clusterName":"example","replicas":3,"selector":{},
+	"template":{"metadata":{},"spec":{"bootstrap":{"dataSecretName":""},
+	"clusterName":"example",
+	"infrastructureRef":{"apiVersion":"infrastructure.cluster.x-k8s.io/v1beta2",
+	"kind":"AWSManagedMachinePool",
+	"name":"example-awsmanagedmachinepool-ng-12345","namespace":"default"},
+	"version":"v1.25"}}},"status":{"availableReplicas":0,"readyReplicas":0,
 	"replicas":0,"unavailableReplicas":0,"updatedReplicas":0}}},
-	"providerConfigRef":{"name":"thingy"},"writeConnectionSecretToRef":{
-	"name":"test-machinepool-ng-23456","namespace":"default"}}}`
+	"providerConfigRef":{"name":"thingy"},
+	"writeConnectionSecretToRef":{"name":"example-machinepool-ng-12345",
+	"namespace":"default"}}}`
 )
 
 type NodegroupErrorMock struct {
@@ -187,7 +614,7 @@ func (n *NodegroupMock) DescribeNodegroup(ctx context.Context,
 				Version:       aws.String("1.25"),
 				CapacityType:  types.CapacityTypesOnDemand,
 				ClusterName:   aws.String("test"),
-				CreatedAt:     aws.Time(time.Now()),
+				CreatedAt:     aws.Time(time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)),
 				InstanceTypes: nil,
 				LaunchTemplate: &types.LaunchTemplateSpecification{
 					Id:      aws.String("lt-123456"),
@@ -215,18 +642,19 @@ func (n *NodegroupMock) DescribeNodegroup(ctx context.Context,
 	case "ng-23456":
 		return &eks.DescribeNodegroupOutput{
 			Nodegroup: &types.Nodegroup{
-				AmiType:       "AL2_x86_64",
-				Version:       aws.String("1.25"),
-				CapacityType:  types.CapacityTypesOnDemand,
-				ClusterName:   aws.String("test"),
-				CreatedAt:     aws.Time(time.Now()),
-				InstanceTypes: nil,
+				AmiType:        "AL2_x86_64",
+				Version:        aws.String("1.25"),
+				ReleaseVersion: aws.String("1.25.16-20240202"),
+				CapacityType:   types.CapacityTypesOnDemand,
+				ClusterName:    aws.String("test"),
+				CreatedAt:      aws.Time(time.Date(2024, time.February, 2, 9, 0, 0, 0, time.UTC)),
+				InstanceTypes:  nil,
 				LaunchTemplate: &types.LaunchTemplateSpecification{
 					Id:      aws.String("lt-234567"),
 					Name:    aws.String("eksctl-test-nodegroup-ng-1"),
 					Version: aws.String("2"),
 				},
-				NodeRole: aws.String("role/eksctl-test-nodegroup-NodeInstanceRole-123456789123"),
+				NodeRole: aws.String("arn:aws:iam::123456789012:role/nodes/eksctl-test-nodegroup-NodeInstanceRole-123456789123"),
 				ScalingConfig: &types.NodegroupScalingConfig{
 					DesiredSize: aws.Int32(1),
 					MaxSize:     aws.Int32(3),
@@ -239,8 +667,24 @@ func (n *NodegroupMock) DescribeNodegroup(ctx context.Context,
 					"subnet-3333333333333333",
 				},
 				NodegroupName: aws.String("ng-23456"),
+				Labels: map[string]string{
+					"role":       "worker",
+					"bad label!": "true",
+				},
+				Taints: []types.Taint{
+					{
+						Effect: types.TaintEffectNoSchedule,
+						Key:    aws.String("dedicated"),
+					},
+				},
+				Tags: map[string]string{
+					"eks:cluster-name": "test",
+					"cost-center":      "1234",
+					"scratch":          "true",
+				},
 				UpdateConfig: &types.NodegroupUpdateConfig{
 					MaxUnavailable: aws.Int32(1),
+					UpdateStrategy: types.NodegroupUpdateStrategiesMinimal,
 				},
 				Resources: &types.NodegroupResources{
 					AutoScalingGroups: []types.AutoScalingGroup{
@@ -275,6 +719,98 @@ func (e *NodegroupMock) ListNodegroups(ctx context.Context,
 	return nil, nil
 }
 
+func (e *NodegroupMock) ListFargateProfiles(ctx context.Context,
+	params *eks.ListFargateProfilesInput,
+	optFns ...func(*eks.Options)) (*eks.ListFargateProfilesOutput, error) {
+	if *params.ClusterName != "test" {
+		return &eks.ListFargateProfilesOutput{}, nil
+	}
+
+	if params.NextToken == nil {
+		return &eks.ListFargateProfilesOutput{
+			FargateProfileNames: []string{"fp-default"},
+			NextToken:           aws.String("page-2"),
+		}, nil
+	}
+	return &eks.ListFargateProfilesOutput{
+		FargateProfileNames: []string{"fp-broken"},
+	}, nil
+}
+
+func (e *NodegroupMock) DescribeFargateProfile(ctx context.Context,
+	params *eks.DescribeFargateProfileInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeFargateProfileOutput, error) {
+	switch *params.FargateProfileName {
+	case "fp-default":
+		return &eks.DescribeFargateProfileOutput{
+			FargateProfile: &types.FargateProfile{
+				ClusterName:         aws.String("test"),
+				FargateProfileName:  aws.String("fp-default"),
+				PodExecutionRoleArn: aws.String("arn:aws:iam::123456789012:role/fargate/test-pod-execution"),
+				Selectors: []types.FargateProfileSelector{
+					{
+						Namespace: aws.String("kube-system"),
+						Labels: map[string]string{
+							"k8s-app": "kube-dns",
+						},
+					},
+					{
+						Namespace: aws.String("serverless"),
+					},
+				},
+				Status: types.FargateProfileStatusActive,
+				Subnets: []string{
+					"subnet-1111111111111111",
+					"subnet-2222222222222222",
+				},
+				Tags: map[string]string{
+					"eks:cluster-name": "test",
+					"cost-center":      "5678",
+				},
+			},
+		}, nil
+	}
+	return &eks.DescribeFargateProfileOutput{
+		FargateProfile: &types.FargateProfile{
+			FargateProfileName:  params.FargateProfileName,
+			PodExecutionRoleArn: aws.String("arn:aws:iam::123456789012:user/not-a-role"),
+		},
+	}, nil
+}
+
+// BadRoleNodegroupMock returns the example nodegroup with a node role that is
+// not an IAM role
+type BadRoleNodegroupMock struct {
+	NodegroupMock
+}
+
+func (n *BadRoleNodegroupMock) DescribeNodegroup(ctx context.Context,
+	params *eks.DescribeNodegroupInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	res, err := n.NodegroupMock.DescribeNodegroup(ctx, params, optFns...)
+	if err == nil && res != nil && res.Nodegroup != nil {
+		res.Nodegroup.NodeRole = aws.String("arn:aws:eks:eu-central-1:123456789012:cluster/example")
+	}
+	return res, err
+}
+
+// NoLaunchTemplateNodegroupMock returns the example nodegroup as created
+// without a launch template
+type NoLaunchTemplateNodegroupMock struct {
+	NodegroupMock
+}
+
+func (n *NoLaunchTemplateNodegroupMock) DescribeNodegroup(ctx context.Context,
+	params *eks.DescribeNodegroupInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	res, err := n.NodegroupMock.DescribeNodegroup(ctx, params, optFns...)
+	if err == nil && res != nil && res.Nodegroup != nil {
+		res.Nodegroup.LaunchTemplate = nil
+		res.Nodegroup.InstanceTypes = []string{"m5.large"}
+	}
+	return res, err
+}
+
 type EmptyEc2Mock struct{}
 
 func (e *EmptyEc2Mock) DescribeLaunchTemplateVersions(ctx context.Context,
@@ -283,12 +819,93 @@ func (e *EmptyEc2Mock) DescribeLaunchTemplateVersions(ctx context.Context,
 	return nil, nil
 }
 
+func (e *EmptyEc2Mock) DescribeImages(ctx context.Context,
+	params *ec2.DescribeImagesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	return nil, nil
+}
+
+func (e *EmptyEc2Mock) DescribeInstances(ctx context.Context,
+	params *ec2.DescribeInstancesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{}, nil
+}
+
 type ValidEc2Mock struct{}
 
+func (e *ValidEc2Mock) DescribeInstances(ctx context.Context,
+	params *ec2.DescribeInstancesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{}, nil
+}
+
+func (e *ValidEc2Mock) DescribeImages(ctx context.Context,
+	params *ec2.DescribeImagesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	switch params.ImageIds[0] {
+	case "ami-0ab553a58389ae35a":
+		return &ec2.DescribeImagesOutput{
+			Images: []ec2types.Image{
+				{
+					ImageId:         aws.String("ami-0ab553a58389ae35a"),
+					ImageOwnerAlias: aws.String("amazon"),
+					Name:            aws.String("amazon-eks-node-1.25-v20240202"),
+					OwnerId:         aws.String("602401143452"),
+				},
+			},
+		}, nil
+	case "ami-0c5bd0a7f9b3e6f2e":
+		return &ec2.DescribeImagesOutput{
+			Images: []ec2types.Image{
+				{
+					ImageId: aws.String("ami-0c5bd0a7f9b3e6f2e"),
+					Name:    aws.String("honeybadger-eks-node-1.25"),
+					OwnerId: aws.String("123456789012"),
+				},
+			},
+		}, nil
+	}
+	return &ec2.DescribeImagesOutput{}, nil
+}
+
 func (e *ValidEc2Mock) DescribeLaunchTemplateVersions(ctx context.Context,
 	params *ec2.DescribeLaunchTemplateVersionsInput,
 	optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
-	switch *params.LaunchTemplateId {
+	// Launch templates of autoscaling groups may be referenced by name alone
+	var template string = aws.ToString(params.LaunchTemplateId)
+	if template == "" {
+		template = aws.ToString(params.LaunchTemplateName)
+	}
+
+	switch template {
+	case "legacy-workers":
+		return &ec2.DescribeLaunchTemplateVersionsOutput{
+			LaunchTemplateVersions: []ec2types.LaunchTemplateVersion{
+				{
+					LaunchTemplateId:   aws.String("lt-345678"),
+					LaunchTemplateName: aws.String("legacy-workers"),
+					LaunchTemplateData: &ec2types.ResponseLaunchTemplateData{
+						IamInstanceProfile: &ec2types.LaunchTemplateIamInstanceProfileSpecification{
+							Name: aws.String("legacy-workers-profile"),
+						},
+						ImageId:          aws.String("ami-0ab553a58389ae35a"),
+						InstanceType:     ec2types.InstanceTypeM5Large,
+						SecurityGroupIds: []string{"sg-44444444444444444"},
+						TagSpecifications: []ec2types.LaunchTemplateTagSpecification{
+							{
+								ResourceType: ec2types.ResourceTypeInstance,
+								Tags: []ec2types.Tag{
+									{Key: aws.String("team"), Value: aws.String("legacy")},
+								},
+							},
+						},
+						UserData: aws.String(base64.StdEncoding.EncodeToString([]byte(
+							"#!/bin/bash\n/etc/eks/bootstrap.sh test --kubelet-extra-args '--max-pods=29'\n"))),
+					},
+					VersionNumber: aws.Int64(4),
+				},
+			},
+		}, nil
 	case "lt-123456":
 		return &ec2.DescribeLaunchTemplateVersionsOutput{
 			LaunchTemplateVersions: []ec2types.LaunchTemplateVersion{
@@ -349,11 +966,41 @@ func (e *ValidEc2Mock) DescribeLaunchTemplateVersions(ctx context.Context,
 						IamInstanceProfile: &ec2types.LaunchTemplateIamInstanceProfileSpecification{
 							Arn: aws.String("arn::123456789:/role/something"),
 						},
-						ImageId: aws.String("ami-0ab553a58389ae35a"),
+						ImageId: aws.String("ami-0c5bd0a7f9b3e6f2e"),
+						MetadataOptions: &ec2types.LaunchTemplateInstanceMetadataOptions{
+							HttpEndpoint:            ec2types.LaunchTemplateInstanceMetadataEndpointStateEnabled,
+							HttpPutResponseHopLimit: aws.Int32(2),
+							HttpTokens:              ec2types.LaunchTemplateHttpTokensStateRequired,
+						},
+						NetworkInterfaces: []ec2types.LaunchTemplateInstanceNetworkInterfaceSpecification{
+							{
+								Groups: []string{
+									"sg-22222222222222222",
+									"sg-33333333333333333",
+								},
+							},
+						},
+						Placement: &ec2types.LaunchTemplatePlacement{
+							Tenancy: ec2types.TenancyDedicated,
+						},
 						SecurityGroupIds: []string{
 							"sg-11111111111111111",
 							"sg-22222222222222222",
 						},
+						TagSpecifications: []ec2types.LaunchTemplateTagSpecification{
+							{
+								ResourceType: ec2types.ResourceTypeInstance,
+								Tags: []ec2types.Tag{
+									{Key: aws.String("team"), Value: aws.String("honeybadger")},
+								},
+							},
+							{
+								ResourceType: ec2types.ResourceTypeVolume,
+								Tags: []ec2types.Tag{
+									{Key: aws.String("backup"), Value: aws.String("daily")},
+								},
+							},
+						},
 						InstanceType: ec2types.InstanceTypeM5Large,
 						KeyName:      aws.String("test-key"),
 					},
@@ -365,6 +1012,38 @@ func (e *ValidEc2Mock) DescribeLaunchTemplateVersions(ctx context.Context,
 	return nil, nil
 }
 
+type ValidIamMock struct{}
+
+func (e *ValidIamMock) ListAttachedRolePolicies(ctx context.Context,
+	params *iam.ListAttachedRolePoliciesInput,
+	optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	if params.Marker == nil {
+		return &iam.ListAttachedRolePoliciesOutput{
+			AttachedPolicies: []iamtypes.AttachedPolicy{
+				{PolicyArn: aws.String("arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy")},
+			},
+			IsTruncated: true,
+			Marker:      aws.String("next"),
+		}, nil
+	}
+	return &iam.ListAttachedRolePoliciesOutput{
+		AttachedPolicies: []iamtypes.AttachedPolicy{
+			{PolicyArn: aws.String("arn:aws:iam::123456789012:policy/nodes-extra")},
+		},
+	}, nil
+}
+
+func (e *ValidIamMock) ListOpenIDConnectProviders(ctx context.Context,
+	params *iam.ListOpenIDConnectProvidersInput,
+	optFns ...func(*iam.Options)) (*iam.ListOpenIDConnectProvidersOutput, error) {
+	return &iam.ListOpenIDConnectProvidersOutput{
+		OpenIDConnectProviderList: []iamtypes.OpenIDConnectProviderListEntry{
+			{Arn: aws.String("arn:aws:iam::123456789012:oidc-provider/oidc.eks.eu-central-1.amazonaws.com/id/OTHER")},
+			{Arn: aws.String("arn:aws:iam::123456789012:oidc-provider/oidc.eks.eu-central-1.amazonaws.com/id/EXAMPLE")},
+		},
+	}, nil
+}
+
 type EmptyAsgMock struct{}
 
 func (e *EmptyAsgMock) DescribeAutoScalingGroups(ctx context.Context,
@@ -373,6 +1052,14 @@ func (e *EmptyAsgMock) DescribeAutoScalingGroups(ctx context.Context,
 	return nil, nil
 }
 
+type FailingAsgMock struct{}
+
+func (e *FailingAsgMock) DescribeAutoScalingGroups(ctx context.Context,
+	params *asg.DescribeAutoScalingGroupsInput,
+	optFns ...func(*asg.Options)) (*asg.DescribeAutoScalingGroupsOutput, error) {
+	return nil, fmt.Errorf("access denied")
+}
+
 type ValidAsgMock struct{}
 
 func (e *ValidAsgMock) DescribeAutoScalingGroups(ctx context.Context,
@@ -412,6 +1099,28 @@ func (e *ValidAsgMock) DescribeAutoScalingGroups(ctx context.Context,
 							},
 						},
 					},
+					Tags: []asgtypes.TagDescription{
+						{
+							Key:               aws.String("eks:nodegroup-name"),
+							Value:             aws.String("ng-23456"),
+							PropagateAtLaunch: aws.Bool(true),
+						},
+						{
+							Key:               aws.String("kubernetes.io/cluster/test"),
+							Value:             aws.String("owned"),
+							PropagateAtLaunch: aws.Bool(true),
+						},
+						{
+							Key:               aws.String("owner"),
+							Value:             aws.String("platform"),
+							PropagateAtLaunch: aws.Bool(true),
+						},
+						{
+							Key:               aws.String("asg-only"),
+							Value:             aws.String("true"),
+							PropagateAtLaunch: aws.Bool(false),
+						},
+					},
 				},
 			},
 		}, nil
@@ -445,70 +1154,436 @@ func (e *ValidAsgMock) DescribeAutoScalingGroups(ctx context.Context,
 	}
 }
 
-func TestRunFunction(t *testing.T) {
+type EmptyNodegroupMock struct{}
 
-	type args struct {
-		ctx context.Context
-		req *fnv1beta1.RunFunctionRequest
-	}
-	type want struct {
-		rsp *fnv1beta1.RunFunctionResponse
-		err error
-	}
+func (n *EmptyNodegroupMock) DescribeNodegroup(ctx context.Context,
+	params *eks.DescribeNodegroupInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	return nil, fmt.Errorf("nodegroup not found")
+}
 
-	type mocks struct {
-		ec2 func(cfg aws.Config) AwsEc2Api
-		eks func(cfg aws.Config) AwsEksApi
-		asg func(cfg aws.Config) AwsAsgApi
-		aws func(region, provider *string) (aws.Config, error)
-	}
+func (n *EmptyNodegroupMock) ListNodegroups(ctx context.Context,
+	params *eks.ListNodegroupsInput,
+	optFns ...func(*eks.Options)) (*eks.ListNodegroupsOutput, error) {
+	return &eks.ListNodegroupsOutput{}, nil
+}
 
-	cases := map[string]struct {
-		reason string
-		args   args
-		want   want
-		mocks  mocks
-	}{
-		"input is undefined": {
-			reason: "When cluster ref is undefined, we get a fatal response",
-			args: args{
-				req: &fnv1beta1.RunFunctionRequest{
-					Input: resource.MustStructObject(&v1beta1.Input{}),
+func (n *EmptyNodegroupMock) ListFargateProfiles(ctx context.Context,
+	params *eks.ListFargateProfilesInput,
+	optFns ...func(*eks.Options)) (*eks.ListFargateProfilesOutput, error) {
+	return &eks.ListFargateProfilesOutput{}, nil
+}
+
+func (n *EmptyNodegroupMock) DescribeFargateProfile(ctx context.Context,
+	params *eks.DescribeFargateProfileInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeFargateProfileOutput, error) {
+	return nil, fmt.Errorf("fargate profile not found")
+}
+
+func (n *EmptyNodegroupMock) DescribeCluster(ctx context.Context,
+	params *eks.DescribeClusterInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	return nil, fmt.Errorf("cluster not found")
+}
+
+func (n *EmptyNodegroupMock) ListIdentityProviderConfigs(ctx context.Context,
+	params *eks.ListIdentityProviderConfigsInput,
+	optFns ...func(*eks.Options)) (*eks.ListIdentityProviderConfigsOutput, error) {
+	return &eks.ListIdentityProviderConfigsOutput{}, nil
+}
+
+func (n *EmptyNodegroupMock) DescribeIdentityProviderConfig(ctx context.Context,
+	params *eks.DescribeIdentityProviderConfigInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeIdentityProviderConfigOutput, error) {
+	return nil, fmt.Errorf("identity provider config not found")
+}
+
+func (n *EmptyNodegroupMock) ListAddons(ctx context.Context,
+	params *eks.ListAddonsInput,
+	optFns ...func(*eks.Options)) (*eks.ListAddonsOutput, error) {
+	return &eks.ListAddonsOutput{}, nil
+}
+
+func (n *EmptyNodegroupMock) DescribeAddon(ctx context.Context,
+	params *eks.DescribeAddonInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeAddonOutput, error) {
+	return nil, fmt.Errorf("add-on not found")
+}
+
+func (n *EmptyNodegroupMock) ListUpdates(ctx context.Context,
+	params *eks.ListUpdatesInput,
+	optFns ...func(*eks.Options)) (*eks.ListUpdatesOutput, error) {
+	return &eks.ListUpdatesOutput{}, nil
+}
+
+func (n *EmptyNodegroupMock) DescribeUpdate(ctx context.Context,
+	params *eks.DescribeUpdateInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeUpdateOutput, error) {
+	return nil, fmt.Errorf("update not found")
+}
+
+// ControlPlaneMock describes a cluster without any nodegroups
+type ControlPlaneMock struct {
+	EmptyNodegroupMock
+}
+
+func (n *ControlPlaneMock) DescribeCluster(ctx context.Context,
+	params *eks.DescribeClusterInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	return &eks.DescribeClusterOutput{
+		Cluster: &types.Cluster{
+			Name:     params.Name,
+			Version:  aws.String("1.29"),
+			Endpoint: aws.String("https://EXAMPLE.gr7.eu-central-1.eks.amazonaws.com"),
+			RoleArn:  aws.String("arn:aws:iam::123456789012:role/eks/test-cluster"),
+			Status:   types.ClusterStatusActive,
+			EncryptionConfig: []types.EncryptionConfig{
+				{
+					Provider: &types.Provider{
+						KeyArn: aws.String("arn:aws:kms:eu-central-1:123456789012:key/1234abcd"),
+					},
+					Resources: []string{"secrets"},
 				},
 			},
-			want: want{
-				rsp: &fnv1beta1.RunFunctionResponse{
-					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
-					Results: []*fnv1beta1.Result{
-						{
-							Severity: fnv1beta1.Severity_SEVERITY_FATAL,
-							Message:  "object does not contain spec field",
-						},
+			Identity: &types.Identity{
+				Oidc: &types.OIDC{
+					Issuer: aws.String("https://oidc.eks.eu-central-1.amazonaws.com/id/EXAMPLE"),
+				},
+			},
+			KubernetesNetworkConfig: &types.KubernetesNetworkConfigResponse{
+				IpFamily:        types.IpFamilyIpv4,
+				ServiceIpv4Cidr: aws.String("172.20.0.0/16"),
+			},
+			Logging: &types.Logging{
+				ClusterLogging: []types.LogSetup{
+					{
+						Enabled: aws.Bool(true),
+						Types:   []types.LogType{types.LogTypeApi, types.LogTypeAudit},
+					},
+					{
+						Enabled: aws.Bool(false),
+						Types:   []types.LogType{types.LogTypeScheduler},
 					},
 				},
 			},
-			mocks: mocks{},
+			ResourcesVpcConfig: &types.VpcConfigResponse{
+				ClusterSecurityGroupId: aws.String("sg-00000000000000000"),
+				EndpointPrivateAccess:  true,
+				EndpointPublicAccess:   true,
+				PublicAccessCidrs:      []string{"203.0.113.0/24"},
+				SecurityGroupIds:       []string{"sg-55555555555555555"},
+				SubnetIds:              []string{"subnet-1111111111111111", "subnet-2222222222222222"},
+				VpcId:                  aws.String("vpc-12345678"),
+			},
+			Tags: map[string]string{
+				"aws:cloudformation:stack-name": "eksctl-test-cluster",
+				"owner":                         "platform",
+			},
 		},
-		"spec is empty": {
-			reason: "the function returns normal if spec is not yet populated",
-			args: args{
-				req: &fnv1beta1.RunFunctionRequest{
-					Meta: &fnv1beta1.RequestMeta{Tag: "hello"},
-					Input: resource.MustStructJSON(`{
-						"apiVersion": "dummy.fn.crossplane.io",
-						"kind": "Input",
-						"spec": {}
-					}`),
-				},
+	}, nil
+}
+
+func (n *ControlPlaneMock) ListIdentityProviderConfigs(ctx context.Context,
+	params *eks.ListIdentityProviderConfigsInput,
+	optFns ...func(*eks.Options)) (*eks.ListIdentityProviderConfigsOutput, error) {
+	if params.NextToken == nil {
+		return &eks.ListIdentityProviderConfigsOutput{
+			IdentityProviderConfigs: []types.IdentityProviderConfig{
+				{Name: aws.String("dex"), Type: aws.String("oidc")},
 			},
-			want: want{
-				rsp: &fnv1beta1.RunFunctionResponse{
-					Meta: &fnv1beta1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
-				},
+			NextToken: aws.String("page-2"),
+		}, nil
+	}
+	return &eks.ListIdentityProviderConfigsOutput{
+		IdentityProviderConfigs: []types.IdentityProviderConfig{
+			{Name: aws.String("okta"), Type: aws.String("oidc")},
+		},
+	}, nil
+}
+
+func (n *ControlPlaneMock) DescribeIdentityProviderConfig(ctx context.Context,
+	params *eks.DescribeIdentityProviderConfigInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeIdentityProviderConfigOutput, error) {
+	return &eks.DescribeIdentityProviderConfigOutput{
+		IdentityProviderConfig: &types.IdentityProviderConfigResponse{
+			Oidc: &types.OidcIdentityProviderConfig{
+				ClientId:                   aws.String("kubernetes"),
+				GroupsClaim:                aws.String("groups"),
+				IdentityProviderConfigName: params.IdentityProviderConfig.Name,
+				IssuerUrl:                  aws.String("https://dex.example.com"),
+				UsernameClaim:              aws.String("email"),
 			},
-			mocks: mocks{},
 		},
-		"function returns fatal if nodegroups cannot be loaded": {
+	}, nil
+}
+
+func (n *ControlPlaneMock) ListAddons(ctx context.Context,
+	params *eks.ListAddonsInput,
+	optFns ...func(*eks.Options)) (*eks.ListAddonsOutput, error) {
+	return &eks.ListAddonsOutput{
+		Addons: []string{"vpc-cni", "coredns", "kube-proxy", "aws-ebs-csi-driver"},
+	}, nil
+}
+
+func (n *ControlPlaneMock) DescribeAddon(ctx context.Context,
+	params *eks.DescribeAddonInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeAddonOutput, error) {
+	var addon *types.Addon = &types.Addon{
+		AddonName: params.AddonName,
+		Status:    types.AddonStatusActive,
+	}
+
+	switch *params.AddonName {
+	case "vpc-cni":
+		addon.AddonVersion = aws.String("v1.16.0-eksbuild.1")
+		addon.ConfigurationValues = aws.String(`{"env":{"ENABLE_PREFIX_DELEGATION":"true"}}`)
+	case "coredns":
+		addon.AddonVersion = aws.String("v1.11.1-eksbuild.4")
+	case "kube-proxy":
+		addon.AddonVersion = aws.String("v1.29.0-eksbuild.1")
+	case "aws-ebs-csi-driver":
+		addon.AddonVersion = aws.String("v1.26.1-eksbuild.1")
+		addon.ServiceAccountRoleArn = aws.String("arn:aws:iam::123456789012:role/ebs-csi-controller")
+	}
+	return &eks.DescribeAddonOutput{Addon: addon}, nil
+}
+
+func (n *ControlPlaneMock) ListUpdates(ctx context.Context,
+	params *eks.ListUpdatesInput,
+	optFns ...func(*eks.Options)) (*eks.ListUpdatesOutput, error) {
+	switch aws.ToString(params.AddonName) {
+	case "vpc-cni":
+		return &eks.ListUpdatesOutput{UpdateIds: []string{"u-2", "u-1"}}, nil
+	case "coredns":
+		return &eks.ListUpdatesOutput{UpdateIds: []string{"u-3"}}, nil
+	case "aws-ebs-csi-driver":
+		return &eks.ListUpdatesOutput{UpdateIds: []string{"u-4"}}, nil
+	}
+	return &eks.ListUpdatesOutput{}, nil
+}
+
+func (n *ControlPlaneMock) DescribeUpdate(ctx context.Context,
+	params *eks.DescribeUpdateInput,
+	optFns ...func(*eks.Options)) (*eks.DescribeUpdateOutput, error) {
+	type update struct {
+		created    time.Time
+		resolution types.ResolveConflicts
+	}
+
+	var updates map[string]update = map[string]update{
+		"u-1": {time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), types.ResolveConflictsOverwrite},
+		"u-2": {time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), types.ResolveConflictsPreserve},
+		"u-3": {time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), types.ResolveConflictsNone},
+		"u-4": {time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), types.ResolveConflictsOverwrite},
+	}
+
+	var u update = updates[*params.UpdateId]
+	return &eks.DescribeUpdateOutput{
+		Update: &types.Update{
+			CreatedAt: aws.Time(u.created),
+			Id:        params.UpdateId,
+			Type:      types.UpdateTypeAddonUpdate,
+			Params: []types.UpdateParam{
+				{Type: types.UpdateParamTypeAddonVersion, Value: aws.String("latest")},
+				{Type: types.UpdateParamTypeResolveConflicts, Value: aws.String(string(u.resolution))},
+			},
+		},
+	}, nil
+}
+
+// KarpenterEc2Mock returns instances launched by Karpenter over two pages
+type KarpenterEc2Mock struct {
+	ValidEc2Mock
+}
+
+func (e *KarpenterEc2Mock) DescribeInstances(ctx context.Context,
+	params *ec2.DescribeInstancesInput,
+	optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	var instance = func(pool, tag string, instanceType ec2types.InstanceType, zone string) ec2types.Instance {
+		return ec2types.Instance{
+			InstanceType: instanceType,
+			Placement:    &ec2types.Placement{AvailabilityZone: aws.String(zone)},
+			Tags: []ec2types.Tag{
+				{Key: aws.String("kubernetes.io/cluster/test"), Value: aws.String("owned")},
+				{Key: aws.String(tag), Value: aws.String(pool)},
+			},
+		}
+	}
+
+	if params.NextToken == nil {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []ec2types.Reservation{
+				{
+					Instances: []ec2types.Instance{
+						instance("default", "karpenter.sh/nodepool", ec2types.InstanceTypeM5Large, "eu-central-1a"),
+						instance("default", "karpenter.sh/nodepool", ec2types.InstanceTypeM5Large, "eu-central-1b"),
+					},
+				},
+				{
+					Instances: []ec2types.Instance{
+						instance("default", "karpenter.sh/nodepool", ec2types.InstanceTypeC5Xlarge, "eu-central-1a"),
+					},
+				},
+			},
+			NextToken: aws.String("page-2"),
+		}, nil
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []ec2types.Reservation{
+			{
+				Instances: []ec2types.Instance{
+					instance("batch", "karpenter.sh/provisioner-name", ec2types.InstanceTypeM5Large, "eu-central-1c"),
+				},
+			},
+		},
+	}, nil
+}
+
+type SelfManagedAsgMock struct{}
+
+func (e *SelfManagedAsgMock) DescribeAutoScalingGroups(ctx context.Context,
+	params *asg.DescribeAutoScalingGroupsInput,
+	optFns ...func(*asg.Options)) (*asg.DescribeAutoScalingGroupsOutput, error) {
+	if aws.ToString(params.NextToken) == "page-2" {
+		return &asg.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []asgtypes.AutoScalingGroup{
+				{
+					AutoScalingGroupName:    aws.String("ancient-workers"),
+					LaunchConfigurationName: aws.String("ancient-workers-20180101"),
+				},
+			},
+		}, nil
+	}
+
+	return &asg.DescribeAutoScalingGroupsOutput{
+		AutoScalingGroups: []asgtypes.AutoScalingGroup{
+			{
+				AutoScalingGroupName: aws.String("eks-ng-23456"),
+				Tags: []asgtypes.TagDescription{
+					{
+						Key:   aws.String("eks:nodegroup-name"),
+						Value: aws.String("ng-23456"),
+					},
+				},
+			},
+			{
+				AutoScalingGroupName: aws.String("legacy-workers"),
+				AvailabilityZones:    []string{"eu-central-1a", "eu-central-1b"},
+				CapacityRebalance:    aws.Bool(true),
+				DefaultCooldown:      aws.Int32(300),
+				DesiredCapacity:      aws.Int32(2),
+				MaxSize:              aws.Int32(5),
+				MinSize:              aws.Int32(1),
+				VPCZoneIdentifier:    aws.String("subnet-1111111111111111,subnet-2222222222222222"),
+				Instances: []asgtypes.Instance{
+					{
+						InstanceId:       aws.String("i-4444444444444444"),
+						AvailabilityZone: aws.String("eu-central-1a"),
+					},
+					{
+						InstanceId:       aws.String("i-5555555555555555"),
+						AvailabilityZone: aws.String("eu-central-1b"),
+					},
+				},
+				MixedInstancesPolicy: &asgtypes.MixedInstancesPolicy{
+					InstancesDistribution: &asgtypes.InstancesDistribution{
+						OnDemandBaseCapacity:                aws.Int32(1),
+						OnDemandPercentageAboveBaseCapacity: aws.Int32(0),
+						SpotAllocationStrategy:              aws.String("price-capacity-optimized"),
+					},
+					LaunchTemplate: &asgtypes.LaunchTemplate{
+						LaunchTemplateSpecification: &asgtypes.LaunchTemplateSpecification{
+							LaunchTemplateName: aws.String("legacy-workers"),
+						},
+						Overrides: []asgtypes.LaunchTemplateOverrides{
+							{InstanceType: aws.String("m5.large")},
+							{InstanceType: aws.String("m5a.large")},
+						},
+					},
+				},
+				Tags: []asgtypes.TagDescription{
+					{
+						Key:               aws.String("kubernetes.io/cluster/test"),
+						Value:             aws.String("owned"),
+						PropagateAtLaunch: aws.Bool(true),
+					},
+					{
+						Key:               aws.String("owner"),
+						Value:             aws.String("platform"),
+						PropagateAtLaunch: aws.Bool(true),
+					},
+				},
+			},
+		},
+		NextToken: aws.String("page-2"),
+	}, nil
+}
+
+func TestRunFunction(t *testing.T) {
+
+	type args struct {
+		ctx context.Context
+		req *fnv1beta1.RunFunctionRequest
+	}
+	type want struct {
+		rsp *fnv1beta1.RunFunctionResponse
+		err error
+	}
+
+	type mocks struct {
+		ec2 func(cfg aws.Config) AwsEc2Api
+		eks func(cfg aws.Config) AwsEksApi
+		asg func(cfg aws.Config) AwsAsgApi
+		iam func(cfg aws.Config) AwsIamApi
+		aws func(region, provider *string) (aws.Config, error)
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+		mocks  mocks
+	}{
+		"input is undefined": {
+			reason: "When cluster ref is undefined, we get a fatal response",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{}),
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_FATAL,
+							Message:  "object does not contain spec field",
+						},
+					},
+				},
+			},
+			mocks: mocks{},
+		},
+		"spec is empty": {
+			reason: "the function returns normal if spec is not yet populated",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Meta: &fnv1beta1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "dummy.fn.crossplane.io",
+						"kind": "Input",
+						"spec": {}
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(response.DefaultTTL)},
+				},
+			},
+			mocks: mocks{},
+		},
+		"function returns fatal if nodegroups cannot be loaded": {
 			args: args{
 				req: &fnv1beta1.RunFunctionRequest{
 					Input: resource.MustStructObject(&v1beta1.Input{
@@ -642,7 +1717,7 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-		"function returns success when nodepool is created test cluster": {
+		"function imports a nodegroup without a launch template": {
 			args: args{
 				req: &fnv1beta1.RunFunctionRequest{
 					Input: resource.MustStructObject(&v1beta1.Input{
@@ -652,21 +1727,21 @@ func TestRunFunction(t *testing.T) {
 					}),
 					Observed: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(xrTest),
+							Resource: resource.MustStructJSON(xrExample),
 						},
 						Resources: map[string]*fnv1beta1.Resource{
 							"eks-cluster": {
-								Resource: resource.MustStructJSON(clusterTest),
+								Resource: resource.MustStructJSON(clusterExample),
 							},
 						},
 					},
 					Desired: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(xrTest),
+							Resource: resource.MustStructJSON(xrExample),
 						},
 						Resources: map[string]*fnv1beta1.Resource{
 							"eks-cluster": {
-								Resource: resource.MustStructJSON(clusterTest),
+								Resource: resource.MustStructJSON(clusterExample),
 							},
 						},
 					},
@@ -677,19 +1752,96 @@ func TestRunFunction(t *testing.T) {
 					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
 					Desired: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(xrTest),
+							Resource: resource.MustStructJSON(xrExample),
 						},
 						Resources: map[string]*fnv1beta1.Resource{
 							"eks-cluster": {
-								Resource: resource.MustStructJSON(clusterTest),
+								Resource: resource.MustStructJSON(clusterExample),
 							},
-							"test-awsmanagedmachinepool-ng-23456": {
+							"example-awsmanagedmachinepool-ng-12345": {
 								Ready:    fnv1beta1.Ready_READY_TRUE,
-								Resource: resource.MustStructJSON(nodepoolTest),
+								Resource: resource.MustStructJSON(nodepoolNoLaunchTemplate),
 							},
-							"test-machinepool-ng-23456": {
+							"example-machinepool-ng-12345": {
 								Ready:    fnv1beta1.Ready_READY_TRUE,
-								Resource: resource.MustStructJSON(machinepoolTest),
+								Resource: resource.MustStructJSON(machinepoolNoLaunchTemplate),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &NoLaunchTemplateNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &ValidAsgMock{}
+				},
+			},
+		},
+		"function generates karpenter manifests from nodegroups": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:        "eks-cluster",
+							GenerateKarpenter: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrExample),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterExample),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrExample),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterExample),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrExample),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterExample),
+							},
+							"example-awsmanagedmachinepool-ng-12345": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(nodepoolExample),
+							},
+							"example-machinepool-ng-12345": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(machinepoolExample),
+							},
+							"example-ec2nodeclass-ng-12345": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(ec2nodeclassExample),
+							},
+							"example-nodepool-ng-12345": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(karpenterNodepoolExample),
 							},
 						},
 					},
@@ -710,23 +1862,907 @@ func TestRunFunction(t *testing.T) {
 				},
 			},
 		},
-	}
-	for name, tc := range cases {
-		t.Run(name, func(t *testing.T) {
-			// set up any required mocks
-			awsConfig = tc.mocks.aws
-			getAsgClient = tc.mocks.asg
-			getEc2Client = tc.mocks.ec2
-			getEksClient = tc.mocks.eks
-
-			f := &Function{log: logging.NewNopLogger()}
-			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
-			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
-			}
-
-			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
+		"function returns success when nodepool is created test cluster": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef: "eks-cluster",
+							Tags: &v1beta1.TagFilter{
+								Exclude: []string{"^scratch$"},
+							},
+							ImportRolePolicies:    true,
+							ImportFargateProfiles: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message:  "nodegroup \"ng-23456\": launch template is pinned to custom AMI ami-0c5bd0a7f9b3e6f2e",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "nodegroup \"ng-23456\": dropping invalid label \"bad label!\": " +
+								"name part must consist of alphanumeric characters, '-', '_' or '.', " +
+								"and must start and end with an alphanumeric character (e.g. 'MyName',  " +
+								"or 'my.name',  or '123-abc', regex used for validation is " +
+								"'([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "nodegroup \"ng-23456\": nodegroup version 1.25 is 4 minor versions " +
+								"behind control plane version 1.29, the supported maximum is 3",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "fargate profile \"fp-broken\" cannot be imported: invalid pod execution role: " +
+								"arn \"arn:aws:iam::123456789012:user/not-a-role\" does not reference an IAM role",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+							"test-awsmanagedmachinepool-ng-23456": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(nodepoolTest),
+							},
+							"test-machinepool-ng-23456": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(machinepoolTest),
+							},
+							"test-awsfargateprofile-fp-default": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(fargateTest),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &NodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &ValidAsgMock{}
+				},
+				iam: func(_ aws.Config) AwsIamApi {
+					return &ValidIamMock{}
+				},
+			},
+		},
+		"function imports self-managed autoscaling groups": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:  "eks-cluster",
+							SelfManaged: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "autoscaling group \"ancient-workers\" cannot be imported: " +
+								"launch configurations are not supported by CAPA",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+							"test-eksconfig-legacy-workers": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(eksconfigLegacy),
+							},
+							"test-awsmachinepool-legacy-workers": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(awsmachinepoolLegacy),
+							},
+							"test-machinepool-legacy-workers": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(machinepoolLegacy),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &EmptyNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &SelfManagedAsgMock{}
+				},
+			},
+		},
+		"function warns when autoscaling groups cannot be listed": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:  "eks-cluster",
+							SelfManaged: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "cannot list autoscaling groups for cluster \"test\", self-managed groups are not imported: " +
+								"access denied",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &EmptyNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &FailingAsgMock{}
+				},
+			},
+		},
+		"function imports the control plane": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:         "eks-cluster",
+							ImportControlPlane: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message:  "cluster \"test\": only identity provider \"dex\" is imported, CAPA cannot hold okta",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "cluster \"test\": add-on \"vpc-cni\" preserves conflicting settings " +
+								"which CAPA cannot do, conflicts will not be resolved",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "cluster \"test\": add-on \"kube-proxy\" has no recorded conflict resolution, " +
+								"set one with a patch such as none to keep CAPA from applying its default",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+							"test-awsmanagedcontrolplane": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(controlplaneTest),
+							},
+							"test-awsmanagedcluster": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(awsmanagedclusterTest),
+							},
+							"test": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(capiClusterTest),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &ControlPlaneMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &EmptyAsgMock{}
+				},
+				iam: func(_ aws.Config) AwsIamApi {
+					return &ValidIamMock{}
+				},
+			},
+		},
+		"function summarises karpenter capacity in the XR status": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:        "eks-cluster",
+							DiscoverKarpenter: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrKarpenterTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &EmptyNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &KarpenterEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &EmptyAsgMock{}
+				},
+			},
+		},
+		"function annotates machinepools for the cluster autoscaler": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef: "eks-cluster",
+							Tags: &v1beta1.TagFilter{
+								Exclude: []string{"^scratch$"},
+							},
+							ImportRolePolicies:    true,
+							ClusterAutoscaler:     true,
+							ImportFargateProfiles: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message:  "nodegroup \"ng-23456\": launch template is pinned to custom AMI ami-0c5bd0a7f9b3e6f2e",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "nodegroup \"ng-23456\": dropping invalid label \"bad label!\": " +
+								"name part must consist of alphanumeric characters, '-', '_' or '.', " +
+								"and must start and end with an alphanumeric character (e.g. 'MyName',  " +
+								"or 'my.name',  or '123-abc', regex used for validation is " +
+								"'([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "nodegroup \"ng-23456\": nodegroup version 1.25 is 4 minor versions " +
+								"behind control plane version 1.29, the supported maximum is 3",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "fargate profile \"fp-broken\" cannot be imported: invalid pod execution role: " +
+								"arn \"arn:aws:iam::123456789012:user/not-a-role\" does not reference an IAM role",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+							"test-awsmanagedmachinepool-ng-23456": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(nodepoolTest),
+							},
+							"test-machinepool-ng-23456": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(machinepoolAutoscalerTest),
+							},
+							"test-awsfargateprofile-fp-default": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(fargateTest),
+							},
+							"test-cluster-autoscaler": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(configmapAutoscalerTest),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &NodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &ValidAsgMock{}
+				},
+				iam: func(_ aws.Config) AwsIamApi {
+					return &ValidIamMock{}
+				},
+			},
+		},
+		"function returns fatal if no namespace can be found": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef: "eks-cluster",
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrUnclaimed),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterExample),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrUnclaimed),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_FATAL,
+							Message: "cannot determine the namespace of generated objects: " +
+								"set targetNamespace in the input, create the XR through a claim " +
+								"or start the function with --default-namespace",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrUnclaimed),
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &EmptyNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &EmptyEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &EmptyAsgMock{}
+				},
+			},
+		},
+		"function warns when a nodegroup cannot be imported": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef: "eks-cluster",
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrExample),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterExample),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrExample),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "nodegroup \"ng-12345\" cannot be imported: invalid node role: " +
+								"arn \"arn:aws:eks:eu-central-1:123456789012:cluster/example\" is not an IAM arn",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrExample),
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &BadRoleNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &ValidAsgMock{}
+				},
+			},
+		},
+		"function warns when the control plane cannot be described": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:         "eks-cluster",
+							SelfManaged:        true,
+							ImportControlPlane: true,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Results: []*fnv1beta1.Result{
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "cannot describe cluster \"test\", the control plane is not imported: " +
+								"cluster not found",
+						},
+						{
+							Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+							Message: "autoscaling group \"ancient-workers\" cannot be imported: " +
+								"launch configurations are not supported by CAPA",
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrTest),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterTest),
+							},
+							"test-eksconfig-legacy-workers": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(eksconfigLegacy),
+							},
+							"test-awsmachinepool-legacy-workers": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(awsmachinepoolLegacy),
+							},
+							"test-machinepool-legacy-workers": {
+								Ready:    fnv1beta1.Ready_READY_TRUE,
+								Resource: resource.MustStructJSON(machinepoolLegacy),
+							},
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &EmptyNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &ValidEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &SelfManagedAsgMock{}
+				},
+				iam: func(_ aws.Config) AwsIamApi {
+					return &ValidIamMock{}
+				},
+			},
+		},
+		"function does not need a namespace for other providers": {
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef: "eks-cluster",
+							Provider:   "azure",
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrUnclaimed),
+						},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {
+								Resource: resource.MustStructJSON(clusterExample),
+							},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrUnclaimed),
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1beta1.RunFunctionResponse{
+					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(xrUnclaimed),
+						},
+					},
+				},
+			},
+			mocks: mocks{
+				aws: func(region, provider *string) (aws.Config, error) {
+					return aws.Config{}, nil
+				},
+				eks: func(_ aws.Config) AwsEksApi {
+					return &EmptyNodegroupMock{}
+				},
+				ec2: func(_ aws.Config) AwsEc2Api {
+					return &EmptyEc2Mock{}
+				},
+				asg: func(_ aws.Config) AwsAsgApi {
+					return &EmptyAsgMock{}
+				},
+			},
+		},
+	}
+
+	var (
+		restoreAws = awsConfig
+		restoreAsg = getAsgClient
+		restoreEc2 = getEc2Client
+		restoreEks = getEksClient
+		restoreIam = getIamClient
+		restoreNow = now
+	)
+	t.Cleanup(func() {
+		awsConfig, getAsgClient, getEc2Client = restoreAws, restoreAsg, restoreEc2
+		getEksClient, getIamClient, now = restoreEks, restoreIam, restoreNow
+	})
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// set up any required mocks
+			awsConfig = tc.mocks.aws
+			getAsgClient = tc.mocks.asg
+			getEc2Client = tc.mocks.ec2
+			getEksClient = tc.mocks.eks
+			getIamClient = tc.mocks.iam
+			now = func() time.Time {
+				return time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+			}
+
+			f := &Function{log: logging.NewNopLogger()}
+			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
+			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionClustersSharingNamespace(t *testing.T) {
+	var (
+		restoreAws = awsConfig
+		restoreAsg = getAsgClient
+		restoreEc2 = getEc2Client
+		restoreEks = getEksClient
+		restoreIam = getIamClient
+	)
+	t.Cleanup(func() {
+		awsConfig, getAsgClient, getEc2Client = restoreAws, restoreAsg, restoreEc2
+		getEksClient, getIamClient = restoreEks, restoreIam
+	})
+
+	awsConfig = func(region, provider *string) (aws.Config, error) { return aws.Config{}, nil }
+	getEksClient = func(_ aws.Config) AwsEksApi { return &NodegroupMock{} }
+	getEc2Client = func(_ aws.Config) AwsEc2Api { return &ValidEc2Mock{} }
+	getAsgClient = func(_ aws.Config) AwsAsgApi { return &ValidAsgMock{} }
+	getIamClient = func(_ aws.Config) AwsIamApi { return &ValidIamMock{} }
+
+	// Both XRs are claimed in the default namespace and their clusters have
+	// nodegroups of the same name
+	var xrs = map[string]struct{ xr, cluster string }{
+		"example": {xrExample, clusterExample},
+		"test":    {xrTest, clusterTest},
+	}
+
+	cases := map[string]struct {
+		template  string
+		wantFatal bool
+	}{
+		"default template": {},
+		"template including the cluster": {
+			template: "{{ .Kind }}-{{ .Name }}-{{ .Cluster }}",
+		},
+		"template without the cluster": {
+			template:  "{{ .Kind }}-{{ .Name }}",
+			wantFatal: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var owners map[string]string = make(map[string]string)
+			for xrName, xr := range xrs {
+				f := &Function{log: logging.NewNopLogger()}
+				rsp, err := f.RunFunction(context.TODO(), &fnv1beta1.RunFunctionRequest{
+					Input: resource.MustStructObject(&v1beta1.Input{
+						Spec: &v1beta1.Spec{
+							ClusterRef:   "eks-cluster",
+							NameTemplate: tc.template,
+						},
+					}),
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(xr.xr)},
+						Resources: map[string]*fnv1beta1.Resource{
+							"eks-cluster": {Resource: resource.MustStructJSON(xr.cluster)},
+						},
+					},
+					Desired: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(xr.xr)},
+					},
+				})
+				if err != nil {
+					t.Fatalf("f.RunFunction(...): unexpected error: %v", err)
+				}
+
+				var fatal bool
+				for _, result := range rsp.GetResults() {
+					fatal = fatal || result.GetSeverity() == fnv1beta1.Severity_SEVERITY_FATAL
+				}
+
+				if fatal != tc.wantFatal {
+					t.Fatalf("f.RunFunction(...): XR %q: want fatal %t, got results %v", xrName, tc.wantFatal, rsp.GetResults())
+				}
+
+				if fatal {
+					continue
+				}
+
+				if len(rsp.GetDesired().GetResources()) == 0 {
+					t.Fatalf("f.RunFunction(...): XR %q generated no objects", xrName)
+				}
+
+				// The wrapping Objects are cluster scoped, so their names
+				// must not be shared between the XRs
+				for _, r := range rsp.GetDesired().GetResources() {
+					objectName, _ := r.GetResource().AsMap()["metadata"].(map[string]any)["name"].(string)
+					if owner, ok := owners[objectName]; ok && owner != xrName {
+						t.Errorf("f.RunFunction(...): Object %q is generated for both XR %q and XR %q", objectName, owner, xrName)
+					}
+					owners[objectName] = xrName
+				}
 			}
 		})
 	}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// roleResourcePrefix is the resource type prefix of an IAM role ARN
+const roleResourcePrefix = "role/"
+
+// parseRoleArn splits an IAM role ARN into the name of the role and its path
+//
+// The resource part of a role ARN is `role/<path><name>` where path is
+// optional and defaults to `/`. A bare `role/...` resource is also accepted.
+func parseRoleArn(role string) (name, path string, err error) {
+	var resource string = role
+	if arn.IsARN(role) {
+		var parsed arn.ARN
+		if parsed, err = arn.Parse(role); err != nil {
+			return "", "", err
+		}
+
+		if parsed.Service != "iam" {
+			return "", "", fmt.Errorf("arn %q is not an IAM arn", role)
+		}
+		resource = parsed.Resource
+	}
+
+	if !strings.HasPrefix(resource, roleResourcePrefix) {
+		return "", "", fmt.Errorf("arn %q does not reference an IAM role", role)
+	}
+
+	resource = strings.TrimPrefix(resource, roleResourcePrefix)
+	var i int = strings.LastIndex(resource, "/")
+	name, path = resource[i+1:], "/"+resource[:i+1]
+
+	if name == "" {
+		return "", "", fmt.Errorf("arn %q has no role name", role)
+	}
+	return name, path, nil
+}
+
+// getRolePolicies lists the ARNs of all managed policies attached to a role
+func getRolePolicies(name string, client AwsIamApi) (policies []string, err error) {
+	var input *iam.ListAttachedRolePoliciesInput = &iam.ListAttachedRolePoliciesInput{
+		RoleName: &name,
+	}
+
+	for {
+		var res *iam.ListAttachedRolePoliciesOutput
+		if res, err = ListAttachedRolePolicies(context.TODO(), client, input); err != nil {
+			return nil, err
+		}
+
+		for _, policy := range res.AttachedPolicies {
+			policies = append(policies, aws.ToString(policy.PolicyArn))
+		}
+
+		if !res.IsTruncated {
+			return policies, nil
+		}
+		input.Marker = res.Marker
+	}
+}
+
+// hasOIDCProvider returns true if an IAM OIDC provider is registered for the
+// issuer. The ARN of an IAM OIDC provider ends with the issuer URL without
+// its scheme.
+func hasOIDCProvider(issuer string, client AwsIamApi) (found bool, err error) {
+	var res *iam.ListOpenIDConnectProvidersOutput
+	if res, err = ListOpenIDConnectProviders(context.TODO(), client, &iam.ListOpenIDConnectProvidersInput{}); err != nil {
+		return false, err
+	}
+
+	var suffix string = ":oidc-provider/" + strings.TrimPrefix(issuer, "https://")
+	for _, provider := range res.OpenIDConnectProviderList {
+		if strings.HasSuffix(aws.ToString(provider.Arn), suffix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
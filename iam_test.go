@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestParseRoleArn(t *testing.T) {
+	cases := map[string]struct {
+		arn      string
+		wantName string
+		wantPath string
+		wantErr  bool
+	}{
+		"role without path": {
+			arn:      "arn:aws:iam::123456789012:role/node-role",
+			wantName: "node-role",
+			wantPath: "/",
+		},
+		"role with path": {
+			arn:      "arn:aws:iam::123456789012:role/platform/nodes/node-role",
+			wantName: "node-role",
+			wantPath: "/platform/nodes/",
+		},
+		"bare role resource": {
+			arn:      "role/node-role",
+			wantName: "node-role",
+			wantPath: "/",
+		},
+		"other partition": {
+			arn:      "arn:aws-cn:iam::123456789012:role/node-role",
+			wantName: "node-role",
+			wantPath: "/",
+		},
+		"not a role": {
+			arn:     "arn:aws:iam::123456789012:user/someone",
+			wantErr: true,
+		},
+		"not an iam arn": {
+			arn:     "arn:aws:s3:::bucket/role/node-role",
+			wantErr: true,
+		},
+		"malformed": {
+			arn:     "node-role",
+			wantErr: true,
+		},
+		"missing name": {
+			arn:     "arn:aws:iam::123456789012:role/nodes/",
+			wantErr: true,
+		},
+		"empty": {
+			arn:     "",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotName, gotPath, err := parseRoleArn(tc.arn)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseRoleArn(%q): unexpected error state: %v", tc.arn, err)
+			}
+
+			if gotName != tc.wantName || gotPath != tc.wantPath {
+				t.Errorf("parseRoleArn(%q): want (%q, %q), got (%q, %q)", tc.arn, tc.wantName, tc.wantPath, gotName, gotPath)
+			}
+		})
+	}
+}
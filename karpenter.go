@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	xfc "github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/composite/v1beta1"
+)
+
+const (
+	// karpenterNodePoolTag is set by Karpenter on the instances it launches
+	karpenterNodePoolTag = "karpenter.sh/nodepool"
+
+	// karpenterProvisionerTag is set by Karpenter releases which predate
+	// NodePools
+	karpenterProvisionerTag = "karpenter.sh/provisioner-name"
+
+	// karpenterStatusPath is where the summary is written on the XR
+	karpenterStatusPath = "status.karpenter"
+)
+
+// discoverKarpenter summarises the instances launched by Karpenter for the
+// cluster in the XR status
+//
+// Karpenter capacity has no autoscaling group, so it is found through the
+// tags Karpenter puts on each instance. Discovery is informational only and
+// failures are reported as warnings.
+func (f *Function) discoverKarpenter(ac *XrConfig, client AwsEc2Api) {
+	var (
+		instances []ec2types.Instance
+		err       error
+	)
+
+	if instances, err = getKarpenterInstances(*ac.cluster, client); err != nil {
+		ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot discover karpenter instances for cluster %q", *ac.cluster))
+		return
+	}
+
+	var status *xfc.KarpenterStatus = summariseKarpenterInstances(instances)
+	f.log.Info("AWSAPI", "Discovered karpenter instances", status.Instances)
+
+	if err = ac.composed.DesiredComposite.Resource.SetValue(karpenterStatusPath, status); err != nil {
+		ac.warnings = append(ac.warnings, errors.Wrap(err, "cannot write karpenter summary to the XR status"))
+	}
+}
+
+// getKarpenterInstances lists the running and pending instances owned by the
+// cluster which carry a Karpenter tag
+func getKarpenterInstances(cluster string, client AwsEc2Api) (instances []ec2types.Instance, err error) {
+	var input *ec2.DescribeInstancesInput = &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("tag:" + clusterTagPrefix + cluster),
+				Values: []string{ownedTagValue},
+			},
+			{
+				Name:   aws.String("tag-key"),
+				Values: []string{karpenterNodePoolTag, karpenterProvisionerTag},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{string(ec2types.InstanceStateNamePending), string(ec2types.InstanceStateNameRunning)},
+			},
+		},
+	}
+
+	for {
+		var res *ec2.DescribeInstancesOutput
+		if res, err = DescribeInstances(context.TODO(), client, input); err != nil {
+			return nil, err
+		}
+
+		for _, reservation := range res.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+
+		if res.NextToken == nil {
+			return instances, nil
+		}
+		input.NextToken = res.NextToken
+	}
+}
+
+// summariseKarpenterInstances counts the instances per NodePool, instance
+// type and zone. NodePools are sorted by name so the status is stable.
+func summariseKarpenterInstances(instances []ec2types.Instance) *xfc.KarpenterStatus {
+	var (
+		status *xfc.KarpenterStatus              = &xfc.KarpenterStatus{}
+		pools  map[string]*xfc.KarpenterNodePool = make(map[string]*xfc.KarpenterNodePool)
+	)
+
+	for _, instance := range instances {
+		var name string = ec2TagValue(instance.Tags, karpenterNodePoolTag)
+		if name == "" {
+			name = ec2TagValue(instance.Tags, karpenterProvisionerTag)
+		}
+
+		pool, ok := pools[name]
+		if !ok {
+			pool = &xfc.KarpenterNodePool{
+				Name:          name,
+				InstanceTypes: make(map[string]int),
+				Zones:         make(map[string]int),
+			}
+			pools[name] = pool
+		}
+
+		pool.Instances++
+		pool.InstanceTypes[string(instance.InstanceType)]++
+		if instance.Placement != nil && instance.Placement.AvailabilityZone != nil {
+			pool.Zones[*instance.Placement.AvailabilityZone]++
+		}
+		status.Instances++
+	}
+
+	for _, pool := range pools {
+		status.NodePools = append(status.NodePools, *pool)
+	}
+	sort.Slice(status.NodePools, func(i, j int) bool {
+		return status.NodePools[i].Name < status.NodePools[j].Name
+	})
+	return status
+}
+
+// ec2TagValue returns the value of the tag with the given key or an empty
+// string if the tag is not set
+func ec2TagValue(tags []ec2types.Tag, key string) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
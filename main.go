@@ -18,6 +18,8 @@ type CLI struct {
 	Address     string `help:"Address at which to listen for gRPC connections." default:":9443"`
 	TLSCertsDir string `help:"Directory containing server certs (tls.key, tls.crt) and the CA used to verify client certificates (ca.crt)" env:"TLS_SERVER_CERTS_DIR"`
 	Insecure    bool   `help:"Run without mTLS credentials. If you supply this flag --tls-server-certs-dir will be ignored."`
+
+	DefaultNamespace string `help:"Namespace generated objects are created in when neither the input nor a claim sets one." env:"DEFAULT_NAMESPACE"`
 }
 
 // Run this Function.
@@ -26,7 +28,7 @@ func (c *CLI) Run() error {
 	log := logging.NewLogrLogger(zl.WithName(composedName))
 	ctrl.SetLogger(zl)
 
-	return function.Serve(&Function{log: log},
+	return function.Serve(&Function{log: log, defaultNamespace: c.DefaultNamespace},
 		function.Listen(c.Network, c.Address),
 		function.MTLSCertificates(c.TLSCertsDir),
 		function.Insecure(c.Insecure))
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+const (
+	// clusterNameLabel is the CAPI label linking an object to its Cluster
+	clusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+	// clusterLabel is the Giant Swarm label naming the cluster of an object
+	clusterLabel = "giantswarm.io/cluster"
+
+	// machinePoolLabel is the Giant Swarm label naming the pool of an object
+	machinePoolLabel = "giantswarm.io/machine-pool"
+)
+
+// propagationRule holds the compiled patterns of a single propagation rule
+type propagationRule struct {
+	kinds       []string
+	allow, deny []*regexp.Regexp
+}
+
+// metadataPolicy decides which labels and annotations of the XR are copied
+// onto each kind of generated object
+type metadataPolicy struct {
+	labels, annotations []propagationRule
+}
+
+// newMetadataPolicy compiles the propagation rules given in the input
+func newMetadataPolicy(spec *v1beta1.PropagationPolicy) (policy *metadataPolicy, err error) {
+	policy = &metadataPolicy{}
+	if spec == nil {
+		return
+	}
+
+	if policy.labels, err = compileRules(spec.Labels); err != nil {
+		return nil, errors.Wrap(err, "invalid label propagation rule")
+	}
+
+	if policy.annotations, err = compileRules(spec.Annotations); err != nil {
+		return nil, errors.Wrap(err, "invalid annotation propagation rule")
+	}
+	return
+}
+
+func compileRules(rules []v1beta1.PropagationRule) (compiled []propagationRule, err error) {
+	for i, rule := range rules {
+		var r propagationRule = propagationRule{
+			kinds: rule.Kinds,
+		}
+
+		if r.allow, err = compilePatterns(rule.Allow); err != nil {
+			return nil, errors.Wrapf(err, "rule %d allow pattern", i)
+		}
+
+		if r.deny, err = compilePatterns(rule.Deny); err != nil {
+			return nil, errors.Wrapf(err, "rule %d deny pattern", i)
+		}
+		compiled = append(compiled, r)
+	}
+	return
+}
+
+// appliesTo returns true if the rule covers objects of the given kind
+func (r *propagationRule) appliesTo(kind string) bool {
+	if len(r.kinds) == 0 {
+		return true
+	}
+
+	for _, k := range r.kinds {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// propagate returns a new map holding the entries of `from` which the rules
+// allow for the given kind. When no rule covers the kind, every entry is
+// copied if `all` is set and none otherwise.
+func propagate(rules []propagationRule, kind string, from map[string]string, all bool) map[string]string {
+	var (
+		into       map[string]string = make(map[string]string)
+		applicable []propagationRule
+	)
+
+	for _, rule := range rules {
+		if rule.appliesTo(kind) {
+			applicable = append(applicable, rule)
+		}
+	}
+
+	for k, v := range from {
+		if len(applicable) == 0 {
+			if all {
+				into[k] = v
+			}
+			continue
+		}
+
+		if propagationAllowed(applicable, k) {
+			into[k] = v
+		}
+	}
+	return into
+}
+
+// propagationAllowed returns true if no rule denies the key and at least one
+// rule allows it. Rules without allow patterns allow every key.
+func propagationAllowed(rules []propagationRule, key string) bool {
+	var allowed bool
+	for _, rule := range rules {
+		for _, re := range rule.deny {
+			if re.MatchString(key) {
+				return false
+			}
+		}
+
+		if len(rule.allow) == 0 {
+			allowed = true
+		}
+
+		for _, re := range rule.allow {
+			if re.MatchString(key) {
+				allowed = true
+			}
+		}
+	}
+	return allowed
+}
+
+// newLabels builds the labels of a generated object of the given kind
+//
+// Every object is given its own map so that labels set for one pool never
+// leak onto objects built for another. The pool label is only set when a
+// pool name is given.
+func newLabels(ac *XrConfig, kind, pool string) map[string]string {
+	var labels map[string]string = propagate(ac.policy.labels, kind, ac.labels, true)
+	for k, v := range ac.additionalLabels {
+		labels[k] = v
+	}
+
+	if override := ac.overrides.get(pool); override != nil {
+		for k, v := range override.Labels {
+			labels[k] = v
+		}
+	}
+
+	labels[clusterNameLabel] = clusterName(ac)
+	labels[clusterLabel] = clusterName(ac)
+
+	// Label values are limited to 63 characters, which the names of
+	// self-managed autoscaling groups may exceed
+	if pool != "" {
+		labels[machinePoolLabel] = shortenName(pool)
+	}
+	return labels
+}
+
+// newAnnotations builds the annotations of a generated object of the given
+// kind. XR annotations are only propagated when a rule allows them.
+func newAnnotations(ac *XrConfig, kind string) map[string]string {
+	return propagate(ac.policy.annotations, kind, ac.annotations, false)
+}
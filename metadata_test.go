@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+func TestPropagate(t *testing.T) {
+	var from map[string]string = map[string]string{
+		"team":                          "platform",
+		"cost-center":                   "1234",
+		"crossplane.io/composite":       "test-abcde",
+		"kubectl.kubernetes.io/restart": "now",
+	}
+
+	cases := map[string]struct {
+		rules []v1beta1.PropagationRule
+		kind  string
+		all   bool
+		want  map[string]string
+	}{
+		"no rules copies everything when all is set": {
+			kind: "MachinePool",
+			all:  true,
+			want: from,
+		},
+		"no rules copies nothing when all is unset": {
+			kind: "MachinePool",
+			want: map[string]string{},
+		},
+		"rule for another kind is ignored": {
+			rules: []v1beta1.PropagationRule{
+				{Kinds: []string{"AWSManagedMachinePool"}, Allow: []string{"^team$"}},
+			},
+			kind: "MachinePool",
+			all:  true,
+			want: from,
+		},
+		"allow patterns select keys": {
+			rules: []v1beta1.PropagationRule{
+				{Kinds: []string{"machinepool"}, Allow: []string{"^team$", "^cost-"}},
+			},
+			kind: "MachinePool",
+			want: map[string]string{"team": "platform", "cost-center": "1234"},
+		},
+		"deny takes precedence over allow": {
+			rules: []v1beta1.PropagationRule{
+				{Allow: []string{".*"}},
+				{Kinds: []string{"MachinePool"}, Deny: []string{`^crossplane\.io/`, `^kubectl\.`}},
+			},
+			kind: "MachinePool",
+			want: map[string]string{"team": "platform", "cost-center": "1234"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rules, err := compileRules(tc.rules)
+			if err != nil {
+				t.Fatalf("compileRules(...): unexpected error: %v", err)
+			}
+
+			got := propagate(rules, tc.kind, from, tc.all)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("propagate(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewMetadataPolicyInvalidPattern(t *testing.T) {
+	_, err := newMetadataPolicy(&v1beta1.PropagationPolicy{
+		Annotations: []v1beta1.PropagationRule{{Allow: []string{"("}}},
+	})
+	if err == nil {
+		t.Errorf("newMetadataPolicy(...): want error for invalid pattern")
+	}
+}
+
+func TestNewLabelsAreIndependent(t *testing.T) {
+	var (
+		cluster string   = "test"
+		ac      XrConfig = XrConfig{
+			cluster:          &cluster,
+			labels:           map[string]string{"team": "platform"},
+			policy:           &metadataPolicy{},
+			additionalLabels: map[string]string{"foo": "bar"},
+		}
+	)
+
+	first := newLabels(&ac, "MachinePool", "ng-1")
+	second := newLabels(&ac, "MachinePool", "ng-2")
+	clusterObject := newLabels(&ac, "Cluster", "")
+
+	if first[machinePoolLabel] != "ng-1" || second[machinePoolLabel] != "ng-2" {
+		t.Errorf("newLabels(...): pool labels leaked between objects: %v, %v", first, second)
+	}
+
+	if _, ok := clusterObject[machinePoolLabel]; ok {
+		t.Errorf("newLabels(...): cluster object given a pool label: %v", clusterObject)
+	}
+
+	var want map[string]string = map[string]string{
+		"team":           "platform",
+		"foo":            "bar",
+		clusterNameLabel: "test",
+		clusterLabel:     "test",
+	}
+	if diff := cmp.Diff(want, clusterObject); diff != "" {
+		t.Errorf("newLabels(...): -want, +got:\n%s", diff)
+	}
+
+	if _, ok := ac.labels[clusterLabel]; ok {
+		t.Errorf("newLabels(...): XR labels were modified: %v", ac.labels)
+	}
+}
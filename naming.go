@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// defaultNameTemplate reproduces the names generated before templates
+	// could be given in the input. The Cluster keeps the name of the EKS
+	// cluster.
+	defaultNameTemplate = `{{ .Cluster }}{{ if ne .Kind "cluster" }}-{{ .Kind }}{{ end }}{{ with .Name }}-{{ . }}{{ end }}`
+
+	// maxNameLength keeps generated names usable as label values
+	maxNameLength = validation.DNS1123LabelMaxLength
+
+	// nameHashLength is the number of hex characters of the hash appended to
+	// shortened names
+	nameHashLength = 8
+)
+
+// defaultTemplate is used when no template was parsed or the template
+// fails to render
+var defaultTemplate = template.Must(template.New("name").Parse(defaultNameTemplate))
+
+// nameTemplateData is given to the name template for every generated object
+type nameTemplateData struct {
+	// Cluster is the name of the EKS cluster
+	Cluster string
+
+	// Kind is the lower case kind of the object, such as `machinepool`
+	Kind string
+
+	// Name is the name of the pool or profile the object is generated for.
+	// It is empty for objects generated once per cluster.
+	Name string
+}
+
+// newNameTemplate parses the name template given in the input and checks it
+// renders a distinct name for each cluster
+//
+// Every object is wrapped in a cluster scoped Object named after it, so a
+// template which does not depend on the cluster makes the objects of clusters
+// sharing a claim namespace overwrite each other. Such templates are rejected.
+func newNameTemplate(text string) (tmpl *template.Template, err error) {
+	if text == "" {
+		text = defaultNameTemplate
+	}
+
+	if tmpl, err = template.New("name").Parse(text); err != nil {
+		return nil, errors.Wrap(err, "invalid name template")
+	}
+
+	for _, object := range []struct{ kind, name string }{
+		{"machinepool", "pool"},
+		{"awsmanagedcontrolplane", ""},
+		{"cluster", ""},
+	} {
+		var names [2]string
+		for i, cluster := range []string{"first", "second"} {
+			if names[i], err = renderName(tmpl, nameTemplateData{Cluster: cluster, Kind: object.kind, Name: object.name}); err != nil {
+				return nil, errors.Wrap(err, "invalid name template")
+			}
+			names[i] = shortenName(sanitizeName(names[i]))
+		}
+
+		if names[0] == names[1] {
+			return nil, fmt.Errorf("name template %q renders the same %s name %q for different clusters, objects of clusters sharing a namespace would collide", text, object.kind, names[0])
+		}
+	}
+	return
+}
+
+func renderName(tmpl *template.Template, data nameTemplateData) (name string, err error) {
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return
+	}
+	return buf.String(), nil
+}
+
+// objectName builds the name of a generated object from the name template
+//
+// The template is checked when the function starts so rendering is not
+// expected to fail. Should it fail, the default template is used.
+func objectName(ac *XrConfig, kind, name string) string {
+	var data nameTemplateData = nameTemplateData{
+		Cluster: *ac.cluster,
+		Kind:    kind,
+		Name:    name,
+	}
+
+	var tmpl *template.Template = ac.nameTemplate
+	if tmpl == nil {
+		tmpl = defaultTemplate
+	}
+
+	rendered, err := renderName(tmpl, data)
+	if err != nil {
+		rendered, _ = renderName(defaultTemplate, data)
+	}
+	return shortenName(sanitizeName(rendered))
+}
+
+// clusterName returns the name of the CAPI Cluster, which every other object
+// refers to
+func clusterName(ac *XrConfig) string {
+	return objectName(ac, "cluster", "")
+}
+
+// sanitizeName turns EKS names, which may hold upper case letters and
+// underscores, into DNS-1123 names. Characters which remain invalid are left
+// for claimName to report.
+func sanitizeName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	return strings.Trim(name, "-.")
+}
+
+// shortenName truncates names longer than maxNameLength and appends a hash
+// of the full name, so that shortened names stay stable and distinct
+func shortenName(name string) string {
+	if len(name) <= maxNameLength {
+		return name
+	}
+
+	var sum [32]byte = sha256.Sum256([]byte(name))
+	var prefix string = strings.TrimRight(name[:maxNameLength-nameHashLength-1], "-.")
+	return prefix + "-" + hex.EncodeToString(sum[:])[:nameHashLength]
+}
+
+// claimName records the name of a generated object and returns an error if
+// the name is not a valid DNS-1123 subdomain or was already given to another
+// object
+//
+// Every object is wrapped in a cluster scoped provider-kubernetes Object of
+// the same name, so names must be unique across all kinds.
+func claimName(ac *XrConfig, name, kind string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("name %q of %s is invalid: %s", name, kind, strings.Join(errs, ", "))
+	}
+
+	if ac.names == nil {
+		ac.names = make(map[string]string)
+	}
+
+	if existing, ok := ac.names[name]; ok {
+		return fmt.Errorf("name %q of %s collides with %s", name, kind, existing)
+	}
+	ac.names[name] = kind
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectName(t *testing.T) {
+	cases := map[string]struct {
+		template string
+		cluster  string
+		kind     string
+		name     string
+		want     string
+		wantErr  bool
+	}{
+		"default template for a pool": {
+			cluster: "test",
+			kind:    "awsmanagedmachinepool",
+			name:    "ng-1",
+			want:    "test-awsmanagedmachinepool-ng-1",
+		},
+		"default template for a cluster object": {
+			cluster: "test",
+			kind:    "awsmanagedcontrolplane",
+			want:    "test-awsmanagedcontrolplane",
+		},
+		"default template for the cluster": {
+			cluster: "test",
+			kind:    "cluster",
+			want:    "test",
+		},
+		"eks names are sanitized": {
+			cluster: "Test_Cluster",
+			kind:    "machinepool",
+			name:    "NG_1",
+			want:    "test-cluster-machinepool-ng-1",
+		},
+		"custom template": {
+			template: "{{ .Name }}-{{ .Kind }}.{{ .Cluster }}",
+			cluster:  "test",
+			kind:     "machinepool",
+			name:     "ng-1",
+			want:     "ng-1-machinepool.test",
+		},
+		"long names are shortened": {
+			cluster: "a-cluster-with-a-rather-long-name",
+			kind:    "awsmanagedmachinepool",
+			name:    "and-a-nodegroup-name-which-is-long-too",
+			want:    "a-cluster-with-a-rather-long-name-awsmanagedmachinepoo-3dc6bf76",
+		},
+		"template without the cluster": {
+			template: "{{ .Kind }}-{{ .Name }}",
+			wantErr:  true,
+		},
+		"template dropping the cluster for one kind": {
+			template: `{{ if ne .Kind "cluster" }}{{ .Cluster }}-{{ end }}{{ .Kind }}{{ with .Name }}-{{ . }}{{ end }}`,
+			wantErr:  true,
+		},
+		"unparsable template": {
+			template: "{{ .Cluster ",
+			wantErr:  true,
+		},
+		"template referencing an unknown field": {
+			template: "{{ .Region }}-{{ .Name }}",
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := newNameTemplate(tc.template)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newNameTemplate(...): unexpected error state: %v", err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			var ac XrConfig = XrConfig{
+				cluster:      &tc.cluster,
+				nameTemplate: tmpl,
+			}
+			got := objectName(&ac, tc.kind, tc.name)
+			if got != tc.want {
+				t.Errorf("objectName(...): want %q, got %q", tc.want, got)
+			}
+
+			if len(got) > maxNameLength {
+				t.Errorf("objectName(...): %q is longer than %d characters", got, maxNameLength)
+			}
+		})
+	}
+}
+
+func TestShortenNameIsDistinct(t *testing.T) {
+	var (
+		prefix string = strings.Repeat("a", maxNameLength)
+		first  string = shortenName(prefix + "-first")
+		second string = shortenName(prefix + "-second")
+	)
+
+	if first == second {
+		t.Errorf("shortenName(...): names sharing a prefix collide: %q", first)
+	}
+
+	if first != shortenName(prefix+"-first") {
+		t.Errorf("shortenName(...): shortened name is not stable")
+	}
+}
+
+func TestClaimName(t *testing.T) {
+	var ac XrConfig
+
+	if err := claimName(&ac, "test-machinepool-ng-1", "MachinePool"); err != nil {
+		t.Errorf("claimName(...): unexpected error: %v", err)
+	}
+
+	if err := claimName(&ac, "test-machinepool-ng-1", "AWSManagedMachinePool"); err == nil {
+		t.Errorf("claimName(...): want error for colliding name")
+	}
+
+	if err := claimName(&ac, "Test_MachinePool", "MachinePool"); err == nil {
+		t.Errorf("claimName(...): want error for invalid name")
+	}
+}
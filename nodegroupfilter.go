@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+// nodegroupSelector holds the compiled fields of a single selector
+type nodegroupSelector struct {
+	name         *regexp.Regexp
+	labels       labels.Selector
+	tags         map[string]string
+	capacityType string
+}
+
+// nodegroupFilter decides which EKS managed nodegroups, self-managed
+// autoscaling groups and fargate profiles are imported
+type nodegroupFilter struct {
+	include, exclude []nodegroupSelector
+}
+
+// newNodegroupFilter compiles the include and exclude selectors given in the
+// input
+func newNodegroupFilter(spec *v1beta1.NodegroupFilter) (filter *nodegroupFilter, err error) {
+	filter = &nodegroupFilter{}
+	if spec == nil {
+		return
+	}
+
+	if filter.include, err = compileSelectors(spec.Include); err != nil {
+		return nil, errors.Wrap(err, "invalid nodegroup include selector")
+	}
+
+	if filter.exclude, err = compileSelectors(spec.Exclude); err != nil {
+		return nil, errors.Wrap(err, "invalid nodegroup exclude selector")
+	}
+	return
+}
+
+func compileSelectors(selectors []v1beta1.NodegroupSelector) (compiled []nodegroupSelector, err error) {
+	for i, selector := range selectors {
+		// An empty selector would match every nodegroup
+		if selector.Name == "" && selector.Labels == nil && len(selector.Tags) == 0 && selector.CapacityType == "" {
+			return nil, fmt.Errorf("selector %d is empty", i)
+		}
+
+		var s nodegroupSelector = nodegroupSelector{
+			tags:         selector.Tags,
+			capacityType: strings.ToUpper(selector.CapacityType),
+		}
+
+		if s.capacityType != "" && !slices.Contains(types.CapacityTypes("").Values(), types.CapacityTypes(s.capacityType)) {
+			return nil, fmt.Errorf("selector %d has unknown capacity type %q", i, selector.CapacityType)
+		}
+
+		if selector.Name != "" {
+			if s.name, err = regexp.Compile(selector.Name); err != nil {
+				return nil, errors.Wrapf(err, "selector %d name", i)
+			}
+		}
+
+		if selector.Labels != nil {
+			if s.labels, err = metav1.LabelSelectorAsSelector(selector.Labels); err != nil {
+				return nil, errors.Wrapf(err, "selector %d labels", i)
+			}
+		}
+		compiled = append(compiled, s)
+	}
+	return
+}
+
+// filterTarget holds what selectors are matched against. Self-managed
+// autoscaling groups and fargate profiles have neither labels nor a capacity
+// type, so selectors using them never match those.
+type filterTarget struct {
+	name         string
+	labels, tags map[string]string
+	capacityType string
+}
+
+// nodegroupTarget describes an EKS managed nodegroup for the filter
+func nodegroupTarget(group *types.Nodegroup) filterTarget {
+	return filterTarget{
+		name:         aws.ToString(group.NodegroupName),
+		labels:       group.Labels,
+		tags:         group.Tags,
+		capacityType: string(group.CapacityType),
+	}
+}
+
+// autoscalingGroupTarget describes a self-managed autoscaling group for the
+// filter
+func autoscalingGroupTarget(group *asgtypes.AutoScalingGroup) filterTarget {
+	var tags map[string]string = make(map[string]string)
+	for _, tag := range group.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return filterTarget{
+		name: aws.ToString(group.AutoScalingGroupName),
+		tags: tags,
+	}
+}
+
+// fargateProfileTarget describes a fargate profile for the filter
+func fargateProfileTarget(profile *types.FargateProfile) filterTarget {
+	return filterTarget{
+		name: aws.ToString(profile.FargateProfileName),
+		tags: profile.Tags,
+	}
+}
+
+// matches returns true if the target matches every field set on the
+// selector
+func (s *nodegroupSelector) matches(target filterTarget) bool {
+	if s.name != nil && !s.name.MatchString(target.name) {
+		return false
+	}
+
+	if s.labels != nil && !s.labels.Matches(labels.Set(target.labels)) {
+		return false
+	}
+
+	for k, v := range s.tags {
+		tag, ok := target.tags[k]
+		if !ok || (v != "" && tag != v) {
+			return false
+		}
+	}
+
+	return s.capacityType == "" || s.capacityType == target.capacityType
+}
+
+// allowed returns true if the target should be imported
+func (f *nodegroupFilter) allowed(target filterTarget) bool {
+	for _, s := range f.exclude {
+		if s.matches(target) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, s := range f.include {
+		if s.matches(target) {
+			return true
+		}
+	}
+	return false
+}
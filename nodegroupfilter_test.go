@@ -0,0 +1,177 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+func TestNodegroupFilter(t *testing.T) {
+	var group *types.Nodegroup = &types.Nodegroup{
+		NodegroupName: aws.String("workers-spot"),
+		CapacityType:  types.CapacityTypesSpot,
+		Labels:        map[string]string{"role": "worker"},
+		Tags:          map[string]string{"team": "platform", "cost-centre": "1234"},
+	}
+
+	cases := map[string]struct {
+		spec    *v1beta1.NodegroupFilter
+		want    bool
+		wantErr bool
+	}{
+		"no filter": {
+			want: true,
+		},
+		"included by name": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{Name: "^workers-"}},
+			},
+			want: true,
+		},
+		"not included": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{Name: "^system-"}},
+			},
+			want: false,
+		},
+		"every field of a selector must match": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{
+					Name:         "^workers-",
+					CapacityType: string(types.CapacityTypesOnDemand),
+				}},
+			},
+			want: false,
+		},
+		"included by labels, tags and capacity type": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{
+					Labels:       &metav1.LabelSelector{MatchLabels: map[string]string{"role": "worker"}},
+					Tags:         map[string]string{"team": "platform", "cost-centre": ""},
+					CapacityType: string(types.CapacityTypesSpot),
+				}},
+			},
+			want: true,
+		},
+		"tag with another value": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{Tags: map[string]string{"team": "apps"}}},
+			},
+			want: false,
+		},
+		"exclusions take precedence": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{Name: "^workers-"}},
+				Exclude: []v1beta1.NodegroupSelector{{CapacityType: string(types.CapacityTypesSpot)}},
+			},
+			want: false,
+		},
+		"capacity type in lower case": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{CapacityType: "spot"}},
+			},
+			want: true,
+		},
+		"empty selector": {
+			spec: &v1beta1.NodegroupFilter{
+				Exclude: []v1beta1.NodegroupSelector{{}},
+			},
+			wantErr: true,
+		},
+		"unknown capacity type": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{CapacityType: "RESERVED"}},
+			},
+			wantErr: true,
+		},
+		"invalid name pattern": {
+			spec: &v1beta1.NodegroupFilter{
+				Include: []v1beta1.NodegroupSelector{{Name: "workers-("}},
+			},
+			wantErr: true,
+		},
+		"invalid label selector": {
+			spec: &v1beta1.NodegroupFilter{
+				Exclude: []v1beta1.NodegroupSelector{{Labels: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "role", Operator: "Matches"}},
+				}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			filter, err := newNodegroupFilter(tc.spec)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newNodegroupFilter(...): unexpected error state: %v", err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := filter.allowed(nodegroupTarget(group)); got != tc.want {
+				t.Errorf("allowed(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNodegroupFilterTargets(t *testing.T) {
+	filter, err := newNodegroupFilter(&v1beta1.NodegroupFilter{
+		Include: []v1beta1.NodegroupSelector{
+			{Name: "^workers-"},
+			{Tags: map[string]string{"team": "platform"}},
+		},
+		Exclude: []v1beta1.NodegroupSelector{{Name: "-legacy$"}},
+	})
+	if err != nil {
+		t.Fatalf("newNodegroupFilter(...): unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		target filterTarget
+		want   bool
+	}{
+		"autoscaling group by name": {
+			target: autoscalingGroupTarget(&asgtypes.AutoScalingGroup{AutoScalingGroupName: aws.String("workers-self")}),
+			want:   true,
+		},
+		"excluded autoscaling group": {
+			target: autoscalingGroupTarget(&asgtypes.AutoScalingGroup{AutoScalingGroupName: aws.String("workers-legacy")}),
+			want:   false,
+		},
+		"autoscaling group by tag": {
+			target: autoscalingGroupTarget(&asgtypes.AutoScalingGroup{
+				AutoScalingGroupName: aws.String("system"),
+				Tags:                 []asgtypes.TagDescription{{Key: aws.String("team"), Value: aws.String("platform")}},
+			}),
+			want: true,
+		},
+		"fargate profile not included": {
+			target: fargateProfileTarget(&types.FargateProfile{FargateProfileName: aws.String("fp-default")}),
+			want:   false,
+		},
+		"fargate profile by tag": {
+			target: fargateProfileTarget(&types.FargateProfile{
+				FargateProfileName: aws.String("fp-default"),
+				Tags:               map[string]string{"team": "platform"},
+			}),
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := filter.allowed(tc.target); got != tc.want {
+				t.Errorf("allowed(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
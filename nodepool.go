@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+// The Karpenter API is not vendored as it pulls in a different release of the
+// Kubernetes libraries. Only the fields populated from a nodegroup are
+// described here, following karpenter.sh/v1 and karpenter.k8s.aws/v1.
+
+// NodePool is a Karpenter NodePool
+type NodePool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              NodePoolSpec `json:"spec"`
+}
+
+// NodePoolSpec describes the nodes a NodePool may launch
+type NodePoolSpec struct {
+	Template NodeClaimTemplate `json:"template"`
+}
+
+// NodeClaimTemplate describes the nodes launched by a NodePool
+type NodeClaimTemplate struct {
+	ObjectMeta NodeClaimTemplateMetadata `json:"metadata,omitempty"`
+	Spec       NodeClaimTemplateSpec     `json:"spec"`
+}
+
+// NodeClaimTemplateMetadata holds the labels given to launched nodes
+type NodeClaimTemplateMetadata struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NodeClaimTemplateSpec holds the scheduling constraints of launched nodes.
+// Requirements carry a merge key so a strategic merge patch can change one
+// requirement without restating the others.
+type NodeClaimTemplateSpec struct {
+	Taints       []v1.Taint                   `json:"taints,omitempty"`
+	Requirements []v1.NodeSelectorRequirement `json:"requirements" patchStrategy:"merge" patchMergeKey:"key"`
+	NodeClassRef NodeClassReference           `json:"nodeClassRef"`
+}
+
+// NodeClassReference points a NodePool at its EC2NodeClass
+type NodeClassReference struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+}
+
+// EC2NodeClass is a Karpenter EC2NodeClass
+type EC2NodeClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              EC2NodeClassSpec `json:"spec"`
+}
+
+// EC2NodeClassSpec describes the EC2 settings of the nodes Karpenter launches
+type EC2NodeClassSpec struct {
+	SubnetSelectorTerms        []SelectorTerm        `json:"subnetSelectorTerms"`
+	SecurityGroupSelectorTerms []SelectorTerm        `json:"securityGroupSelectorTerms"`
+	AMISelectorTerms           []AMISelectorTerm     `json:"amiSelectorTerms"`
+	AMIFamily                  *string               `json:"amiFamily,omitempty"`
+	Role                       string                `json:"role,omitempty"`
+	InstanceProfile            *string               `json:"instanceProfile,omitempty"`
+	Tags                       map[string]string     `json:"tags,omitempty"`
+	MetadataOptions            *MetadataOptions      `json:"metadataOptions,omitempty"`
+	BlockDeviceMappings        []*BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
+}
+
+// SelectorTerm selects subnets or security groups by ID or by tags
+type SelectorTerm struct {
+	Tags map[string]string `json:"tags,omitempty"`
+	ID   string            `json:"id,omitempty"`
+}
+
+// AMISelectorTerm selects AMIs by alias or by ID
+type AMISelectorTerm struct {
+	Alias string `json:"alias,omitempty"`
+	ID    string `json:"id,omitempty"`
+}
+
+// MetadataOptions holds the instance metadata service settings
+type MetadataOptions struct {
+	HTTPEndpoint            *string `json:"httpEndpoint,omitempty"`
+	HTTPPutResponseHopLimit *int64  `json:"httpPutResponseHopLimit,omitempty"`
+	HTTPTokens              *string `json:"httpTokens,omitempty"`
+}
+
+// BlockDeviceMapping describes a volume attached to launched nodes
+type BlockDeviceMapping struct {
+	DeviceName *string      `json:"deviceName,omitempty"`
+	EBS        *BlockDevice `json:"ebs,omitempty"`
+	RootVolume bool         `json:"rootVolume,omitempty"`
+}
+
+// BlockDevice holds the EBS settings of a volume
+type BlockDevice struct {
+	Encrypted  *bool   `json:"encrypted,omitempty"`
+	IOPS       *int64  `json:"iops,omitempty"`
+	KMSKeyID   *string `json:"kmsKeyID,omitempty"`
+	Throughput *int64  `json:"throughput,omitempty"`
+	VolumeSize *string `json:"volumeSize,omitempty"`
+	VolumeType *string `json:"volumeType,omitempty"`
+}
+
+const (
+	// karpenterNodeClassGroup is the API group of the EC2NodeClass
+	karpenterNodeClassGroup = "karpenter.k8s.aws"
+
+	// karpenterCapacityTypeLabel is the well known label holding the capacity type
+	karpenterCapacityTypeLabel = "karpenter.sh/capacity-type"
+
+	// eksClusterNameTag is set by EKS on the cluster security group
+	eksClusterNameTag = "aws:eks:cluster-name"
+)
+
+// karpenterCapacityTypes maps the nodegroup capacity types onto Karpenter
+var karpenterCapacityTypes map[types.CapacityTypes]string = map[types.CapacityTypes]string{
+	types.CapacityTypesOnDemand: "on-demand",
+	types.CapacityTypesSpot:     "spot",
+}
+
+// karpenterTaintEffects maps the CAPA taint effects back onto Kubernetes
+var karpenterTaintEffects map[expinfrav2.TaintEffect]v1.TaintEffect = map[expinfrav2.TaintEffect]v1.TaintEffect{
+	expinfrav2.TaintEffectNoSchedule:       v1.TaintEffectNoSchedule,
+	expinfrav2.TaintEffectNoExecute:        v1.TaintEffectNoExecute,
+	expinfrav2.TaintEffectPreferNoSchedule: v1.TaintEffectPreferNoSchedule,
+}
+
+// karpenterAMIAliases are the Karpenter aliases of the EKS optimized AMIs of
+// each family. Windows aliases depend on the release and are built from it
+// instead.
+var karpenterAMIAliases map[amiFamily]string = map[amiFamily]string{
+	amiFamilyAmazonLinux2:    "al2",
+	amiFamilyAmazonLinux2023: "al2023",
+	amiFamilyBottlerocket:    "bottlerocket",
+}
+
+// karpenterAMIFamilies are the Karpenter AMI families given alongside an AMI
+// selected by ID. Windows families depend on the release and are built from
+// it instead.
+var karpenterAMIFamilies map[amiFamily]string = map[amiFamily]string{
+	amiFamilyAmazonLinux2:    "AL2",
+	amiFamilyAmazonLinux2023: "AL2023",
+	amiFamilyBottlerocket:    "Bottlerocket",
+	amiFamilyCustom:          "Custom",
+}
+
+// nodegroupToKarpenter converts a nodegroup and the CAPA spec built for it
+// into a Karpenter NodePool and EC2NodeClass
+//
+// Settings with no Karpenter equivalent are reported as warnings.
+func nodegroupToKarpenter(cluster, nodePoolName, nodeClassName string, group *types.Nodegroup, ng *NodegroupConfig) (nodePool *NodePool, nodeClass *EC2NodeClass, warnings []error) {
+	var (
+		pool     *expinfrav2.AWSManagedMachinePoolSpec = ng.spec
+		template *expinfrav2.AWSLaunchTemplate         = pool.AWSLaunchTemplate
+	)
+
+	nodeClass = &EC2NodeClass{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "EC2NodeClass",
+			APIVersion: "karpenter.k8s.aws/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeClassName,
+		},
+		Spec: EC2NodeClassSpec{
+			Tags: pool.AdditionalTags,
+		},
+	}
+
+	for _, subnet := range pool.SubnetIDs {
+		nodeClass.Spec.SubnetSelectorTerms = append(nodeClass.Spec.SubnetSelectorTerms, SelectorTerm{ID: subnet})
+	}
+
+	nodePool = &NodePool{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "NodePool",
+			APIVersion: "karpenter.sh/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodePoolName,
+		},
+		Spec: NodePoolSpec{
+			Template: NodeClaimTemplate{
+				ObjectMeta: NodeClaimTemplateMetadata{
+					Labels: pool.Labels,
+				},
+				Spec: NodeClaimTemplateSpec{
+					NodeClassRef: NodeClassReference{
+						Group: karpenterNodeClassGroup,
+						Kind:  nodeClass.Kind,
+						Name:  nodeClass.Name,
+					},
+				},
+			},
+		},
+	}
+
+	var instanceTypes []string = group.InstanceTypes
+	if len(instanceTypes) == 0 && template != nil && template.InstanceType != "" {
+		instanceTypes = []string{template.InstanceType}
+	}
+
+	var requirements *[]v1.NodeSelectorRequirement = &nodePool.Spec.Template.Spec.Requirements
+	addRequirement(requirements, v1.LabelInstanceTypeStable, instanceTypes...)
+	addRequirement(requirements, v1.LabelTopologyZone, pool.AvailabilityZones...)
+
+	if strings.Contains(string(group.AmiType), "ARM_64") {
+		addRequirement(requirements, v1.LabelArchStable, "arm64")
+	} else if group.AmiType != "" && group.AmiType != types.AMITypesCustom {
+		addRequirement(requirements, v1.LabelArchStable, "amd64")
+	}
+
+	if capacity, ok := karpenterCapacityTypes[group.CapacityType]; ok {
+		addRequirement(requirements, karpenterCapacityTypeLabel, capacity)
+	} else if group.CapacityType != "" {
+		warnings = append(warnings, fmt.Errorf("capacity type %s has no Karpenter equivalent", group.CapacityType))
+	}
+
+	for _, taint := range pool.Taints {
+		nodePool.Spec.Template.Spec.Taints = append(nodePool.Spec.Template.Spec.Taints, v1.Taint{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: karpenterTaintEffects[taint.Effect],
+		})
+	}
+
+	var warning error
+	if nodeClass.Spec.AMISelectorTerms, nodeClass.Spec.AMIFamily, warning = karpenterAMISelector(group.AmiType, ng.family, ng.windowsRelease, template); warning != nil {
+		warnings = append(warnings, warning)
+	}
+
+	if template != nil {
+		for _, sg := range template.AdditionalSecurityGroups {
+			if sg.ID != nil {
+				nodeClass.Spec.SecurityGroupSelectorTerms = append(nodeClass.Spec.SecurityGroupSelectorTerms, SelectorTerm{ID: *sg.ID})
+			}
+		}
+
+		if template.IamInstanceProfile != "" {
+			var profile string = instanceProfileName(template.IamInstanceProfile)
+			nodeClass.Spec.InstanceProfile = &profile
+		}
+
+		if options := template.InstanceMetadataOptions; options != nil {
+			nodeClass.Spec.MetadataOptions = &MetadataOptions{
+				HTTPEndpoint: optionalString(string(options.HTTPEndpoint)),
+				HTTPTokens:   optionalString(string(options.HTTPTokens)),
+			}
+			if options.HTTPPutResponseHopLimit != 0 {
+				nodeClass.Spec.MetadataOptions.HTTPPutResponseHopLimit = &options.HTTPPutResponseHopLimit
+			}
+		}
+
+		if template.RootVolume != nil {
+			nodeClass.Spec.BlockDeviceMappings = append(nodeClass.Spec.BlockDeviceMappings, rootBlockDevice(template.RootVolume))
+		}
+	}
+
+	// Without security groups on the launch template, nodegroup instances use
+	// the cluster security group created by EKS
+	if len(nodeClass.Spec.SecurityGroupSelectorTerms) == 0 {
+		nodeClass.Spec.SecurityGroupSelectorTerms = []SelectorTerm{
+			{Tags: map[string]string{eksClusterNameTag: cluster}},
+		}
+	}
+
+	if nodeClass.Spec.InstanceProfile == nil {
+		nodeClass.Spec.Role = pool.RoleName
+	}
+
+	if len(nodeClass.Spec.BlockDeviceMappings) == 0 && pool.DiskSize != nil {
+		nodeClass.Spec.BlockDeviceMappings = append(nodeClass.Spec.BlockDeviceMappings, rootBlockDevice(&infrav2.Volume{
+			Size: int64(*pool.DiskSize),
+		}))
+	}
+	return nodePool, nodeClass, warnings
+}
+
+// karpenterAMISelector selects the AMI used by the nodegroup
+//
+// EKS optimized AMIs are selected through the alias of their family so that
+// Karpenter follows new releases in the same way as the SSM lookup used by
+// CAPA. AMIs pinned by ID stay pinned and are given the Karpenter AMI family
+// matching their own.
+func karpenterAMISelector(amiType types.AMITypes, family amiFamily, release string, template *expinfrav2.AWSLaunchTemplate) (terms []AMISelectorTerm, karpenterFamily *string, warning error) {
+	if template != nil && template.AMI.ID != nil {
+		mapped, ok := karpenterAMIFamilies[family]
+		if family == amiFamilyWindows && release != "" {
+			mapped, ok = "Windows"+release, true
+		}
+
+		if !ok {
+			mapped = karpenterAMIFamilies[amiFamilyCustom]
+		}
+		return []AMISelectorTerm{{ID: *template.AMI.ID}}, &mapped, nil
+	}
+
+	var alias string = karpenterAMIAliases[family]
+	if family == amiFamilyWindows && release != "" {
+		alias = "windows" + release
+	}
+
+	if alias == "" {
+		return nil, nil, fmt.Errorf("AMI type %q has no Karpenter alias, amiSelectorTerms must be set by hand", amiType)
+	}
+	return []AMISelectorTerm{{Alias: alias + "@latest"}}, nil, nil
+}
+
+// addRequirement adds an `In` requirement if there are any values
+func addRequirement(requirements *[]v1.NodeSelectorRequirement, key string, values ...string) {
+	if len(values) == 0 {
+		return
+	}
+
+	*requirements = append(*requirements, v1.NodeSelectorRequirement{
+		Key:      key,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   values,
+	})
+}
+
+// instanceProfileName returns the name of an instance profile which may be
+// given as an ARN
+func instanceProfileName(profile string) string {
+	if !arn.IsARN(profile) {
+		return profile
+	}
+
+	var i int = strings.LastIndex(profile, "/")
+	return profile[i+1:]
+}
+
+// rootBlockDevice converts a CAPA root volume into a Karpenter block device
+func rootBlockDevice(volume *infrav2.Volume) *BlockDeviceMapping {
+	var (
+		size   string = fmt.Sprintf("%dGi", volume.Size)
+		device string = volume.DeviceName
+	)
+	if device == "" {
+		device = "/dev/xvda"
+	}
+
+	var mapping *BlockDeviceMapping = &BlockDeviceMapping{
+		DeviceName: &device,
+		RootVolume: true,
+		EBS: &BlockDevice{
+			Encrypted:  volume.Encrypted,
+			KMSKeyID:   optionalString(volume.EncryptionKey),
+			Throughput: volume.Throughput,
+			VolumeSize: &size,
+			VolumeType: optionalString(string(volume.Type)),
+		},
+	}
+
+	if volume.IOPS != 0 {
+		mapping.EBS.IOPS = &volume.IOPS
+	}
+	return mapping
+}
+
+// optionalString returns nil for an empty string
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
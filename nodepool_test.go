@@ -0,0 +1,176 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+func TestKarpenterAMISelector(t *testing.T) {
+	cases := map[string]struct {
+		amiType    types.AMITypes
+		family     amiFamily
+		release    string
+		template   *expinfrav2.AWSLaunchTemplate
+		want       []AMISelectorTerm
+		wantFamily *string
+		wantWarn   bool
+	}{
+		"AL2 AMI type": {
+			amiType: types.AMITypesAl2X8664,
+			family:  amiFamilyAmazonLinux2,
+			want:    []AMISelectorTerm{{Alias: "al2@latest"}},
+		},
+		"AL2023 AMI type": {
+			amiType: types.AMITypesAl2023Arm64Standard,
+			family:  amiFamilyAmazonLinux2023,
+			want:    []AMISelectorTerm{{Alias: "al2023@latest"}},
+		},
+		"Bottlerocket AMI type": {
+			amiType: types.AMITypesBottlerocketX8664,
+			family:  amiFamilyBottlerocket,
+			want:    []AMISelectorTerm{{Alias: "bottlerocket@latest"}},
+		},
+		"Windows 2022 AMI type": {
+			amiType: types.AMITypesWindowsCore2022X8664,
+			family:  amiFamilyWindows,
+			release: "2022",
+			want:    []AMISelectorTerm{{Alias: "windows2022@latest"}},
+		},
+		"EKS optimized lookup": {
+			amiType: types.AMITypesCustom,
+			family:  amiFamilyAmazonLinux2,
+			template: &expinfrav2.AWSLaunchTemplate{
+				AMI: infrav2.AMIReference{EKSOptimizedLookupType: lookupTypePtr(infrav2.AmazonLinux)},
+			},
+			want: []AMISelectorTerm{{Alias: "al2@latest"}},
+		},
+		"pinned EKS optimized AMI": {
+			amiType: types.AMITypesCustom,
+			family:  amiFamilyAmazonLinux2023,
+			template: &expinfrav2.AWSLaunchTemplate{
+				AMI: infrav2.AMIReference{ID: aws.String("ami-0123456789abcdef0")},
+			},
+			want:       []AMISelectorTerm{{ID: "ami-0123456789abcdef0"}},
+			wantFamily: aws.String("AL2023"),
+		},
+		"pinned EKS optimized Windows AMI": {
+			amiType: types.AMITypesCustom,
+			family:  amiFamilyWindows,
+			release: "2019",
+			template: &expinfrav2.AWSLaunchTemplate{
+				AMI: infrav2.AMIReference{ID: aws.String("ami-0fedcba9876543210")},
+			},
+			want:       []AMISelectorTerm{{ID: "ami-0fedcba9876543210"}},
+			wantFamily: aws.String("Windows2019"),
+		},
+		"pinned Windows AMI of an unknown release": {
+			amiType: types.AMITypesCustom,
+			family:  amiFamilyWindows,
+			template: &expinfrav2.AWSLaunchTemplate{
+				AMI: infrav2.AMIReference{ID: aws.String("ami-0fedcba9876543210")},
+			},
+			want:       []AMISelectorTerm{{ID: "ami-0fedcba9876543210"}},
+			wantFamily: aws.String("Custom"),
+		},
+		"custom AMI": {
+			amiType: types.AMITypesCustom,
+			family:  amiFamilyCustom,
+			template: &expinfrav2.AWSLaunchTemplate{
+				AMI: infrav2.AMIReference{ID: aws.String("ami-0c5bd0a7f9b3e6f2e")},
+			},
+			want:       []AMISelectorTerm{{ID: "ami-0c5bd0a7f9b3e6f2e"}},
+			wantFamily: aws.String("Custom"),
+		},
+		"custom AMI type without an image": {
+			amiType:  types.AMITypesCustom,
+			family:   amiFamilyCustom,
+			wantWarn: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, family, warning := karpenterAMISelector(tc.amiType, tc.family, tc.release, tc.template)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("karpenterAMISelector(...): -want, +got:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.wantFamily, family); diff != "" {
+				t.Errorf("karpenterAMISelector(...): -want family, +got family:\n%s", diff)
+			}
+
+			if (warning != nil) != tc.wantWarn {
+				t.Errorf("karpenterAMISelector(...): unexpected warning state: %v", warning)
+			}
+		})
+	}
+}
+
+func lookupTypePtr(t infrav2.EKSAMILookupType) *infrav2.EKSAMILookupType {
+	return &t
+}
+
+func TestNodegroupToKarpenter(t *testing.T) {
+	var (
+		group *types.Nodegroup = &types.Nodegroup{
+			AmiType:       types.AMITypesAl2Arm64,
+			CapacityType:  types.CapacityTypesSpot,
+			InstanceTypes: []string{"m6g.large", "m7g.large"},
+		}
+		ng *NodegroupConfig = &NodegroupConfig{
+			family: amiFamilyAmazonLinux2,
+			spec: &expinfrav2.AWSManagedMachinePoolSpec{
+				AvailabilityZones: []string{"eu-central-1a"},
+				Labels:            map[string]string{"role": "worker"},
+				RoleName:          "nodes",
+				SubnetIDs:         []string{"subnet-1111111111111111"},
+				Taints: expinfrav2.Taints{
+					{Key: "dedicated", Value: "batch", Effect: expinfrav2.TaintEffectNoSchedule},
+				},
+				AWSLaunchTemplate: &expinfrav2.AWSLaunchTemplate{
+					IamInstanceProfile: "arn:aws:iam::123456789012:instance-profile/nodes/workers",
+				},
+			},
+		}
+	)
+
+	nodePool, nodeClass, warnings := nodegroupToKarpenter("test", "test-nodepool-ng-1", "test-ec2nodeclass-ng-1", group, ng)
+	if len(warnings) != 0 {
+		t.Errorf("nodegroupToKarpenter(...): unexpected warnings: %v", warnings)
+	}
+
+	var wantRequirements []v1.NodeSelectorRequirement = []v1.NodeSelectorRequirement{
+		{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"m6g.large", "m7g.large"}},
+		{Key: v1.LabelTopologyZone, Operator: v1.NodeSelectorOpIn, Values: []string{"eu-central-1a"}},
+		{Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{"arm64"}},
+		{Key: karpenterCapacityTypeLabel, Operator: v1.NodeSelectorOpIn, Values: []string{"spot"}},
+	}
+	if diff := cmp.Diff(wantRequirements, nodePool.Spec.Template.Spec.Requirements); diff != "" {
+		t.Errorf("nodegroupToKarpenter(...): -want requirements, +got requirements:\n%s", diff)
+	}
+
+	var wantTaints []v1.Taint = []v1.Taint{
+		{Key: "dedicated", Value: "batch", Effect: v1.TaintEffectNoSchedule},
+	}
+	if diff := cmp.Diff(wantTaints, nodePool.Spec.Template.Spec.Taints); diff != "" {
+		t.Errorf("nodegroupToKarpenter(...): -want taints, +got taints:\n%s", diff)
+	}
+
+	if nodeClass.Spec.Role != "" || aws.ToString(nodeClass.Spec.InstanceProfile) != "workers" {
+		t.Errorf("nodegroupToKarpenter(...): want instance profile workers and no role, got %q and %q",
+			aws.ToString(nodeClass.Spec.InstanceProfile), nodeClass.Spec.Role)
+	}
+
+	var wantSecurityGroups []SelectorTerm = []SelectorTerm{
+		{Tags: map[string]string{eksClusterNameTag: "test"}},
+	}
+	if diff := cmp.Diff(wantSecurityGroups, nodeClass.Spec.SecurityGroupSelectorTerms); diff != "" {
+		t.Errorf("nodegroupToKarpenter(...): -want security groups, +got security groups:\n%s", diff)
+	}
+}
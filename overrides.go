@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+// deletionPolicies are the deletion policies provider-kubernetes accepts
+var deletionPolicies = []string{"Orphan", "Delete"}
+
+// amiIDPattern matches the IDs of EC2 images
+var amiIDPattern = regexp.MustCompile(`^ami-[0-9a-f]{8}([0-9a-f]{9})?$`)
+
+// nodegroupOverrides holds the overrides given in the input and records
+// which of them matched an imported pool
+type nodegroupOverrides struct {
+	overrides map[string]v1beta1.NodegroupOverride
+	matched   map[string]bool
+}
+
+// newNodegroupOverrides validates the overrides given in the input. Deletion
+// policies, AMI IDs and labels which are not valid are dropped and returned as
+// warnings.
+func newNodegroupOverrides(spec map[string]v1beta1.NodegroupOverride) (o *nodegroupOverrides, warnings []error) {
+	o = &nodegroupOverrides{
+		overrides: make(map[string]v1beta1.NodegroupOverride),
+		matched:   make(map[string]bool),
+	}
+
+	for name, override := range spec {
+		if override.DeletionPolicy != "" && !slices.Contains(deletionPolicies, override.DeletionPolicy) {
+			warnings = append(warnings, fmt.Errorf("nodegroup override %q: deletion policy %q is not one of %s", name, override.DeletionPolicy, strings.Join(deletionPolicies, ", ")))
+			override.DeletionPolicy = ""
+		}
+
+		if override.AMIID != "" && !amiIDPattern.MatchString(override.AMIID) {
+			warnings = append(warnings, fmt.Errorf("nodegroup override %q: %q is not an AMI ID", name, override.AMIID))
+			override.AMIID = ""
+		}
+
+		var labels map[string]string = make(map[string]string)
+		for k, v := range override.Labels {
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				warnings = append(warnings, fmt.Errorf("nodegroup override %q: label key %q is invalid: %s", name, k, strings.Join(errs, ", ")))
+				continue
+			}
+
+			if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+				warnings = append(warnings, fmt.Errorf("nodegroup override %q: value of label %q is invalid: %s", name, k, strings.Join(errs, ", ")))
+				continue
+			}
+			labels[k] = v
+		}
+		override.Labels = labels
+		o.overrides[name] = override
+	}
+	return
+}
+
+// get returns the override of the pool and records it as matched. Pools
+// without an override return nil.
+func (o *nodegroupOverrides) get(pool string) *v1beta1.NodegroupOverride {
+	if o == nil {
+		return nil
+	}
+
+	override, ok := o.overrides[pool]
+	if !ok {
+		return nil
+	}
+	o.matched[pool] = true
+	return &override
+}
+
+// unmatched returns a warning for every override which matched no imported
+// pool, usually a typo or a pool which was removed or filtered out
+func (o *nodegroupOverrides) unmatched() (warnings []error) {
+	if o == nil {
+		return
+	}
+
+	var names []string
+	for name := range o.overrides {
+		if !o.matched[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		warnings = append(warnings, fmt.Errorf("nodegroup override %q matches no imported nodegroup", name))
+	}
+	return
+}
+
+// applyLaunchTemplateOverride sets the instance profile and AMI of the
+// override on the launch template of the pool
+//
+// Pools without a launch template are left alone and reported as a warning
+// as CAPA has nowhere to put either.
+func applyLaunchTemplateOverride(override *v1beta1.NodegroupOverride, template *expinfrav2.AWSLaunchTemplate, annotations map[string]string) (warning error) {
+	if override == nil || (override.InstanceProfile == "" && override.AMIID == "") {
+		return
+	}
+
+	if template == nil {
+		return errors.New("instanceProfile and amiID overrides need a launch template, which the pool does not have")
+	}
+
+	if override.InstanceProfile != "" {
+		template.IamInstanceProfile = override.InstanceProfile
+	}
+
+	// A pinned AMI replaces any EKS optimized lookup set up when the launch
+	// template was imported
+	if override.AMIID != "" {
+		var id string = override.AMIID
+		template.AMI = infrav2.AMIReference{
+			ID: &id,
+		}
+		delete(annotations, amiIDAnnotation)
+		annotations[amiPinnedAnnotation] = "true"
+	}
+	return
+}
+
+// pinManagedAMI switches an EKS managed pool pinned to an AMI by an override
+// to the CUSTOM AMI type, which EKS requires for launch templates naming an
+// image. The release version only applies to AMIs published by AWS.
+func pinManagedAMI(override *v1beta1.NodegroupOverride, spec *expinfrav2.AWSManagedMachinePoolSpec) {
+	if override == nil || override.AMIID == "" || spec.AWSLaunchTemplate == nil {
+		return
+	}
+
+	var custom expinfrav2.ManagedMachineAMIType = expinfrav2.ManagedMachineAMIType(types.AMITypesCustom)
+	spec.AMIType = &custom
+	spec.AMIVersion = nil
+}
+
+// setDeletionPolicy records the deletion policy of the override for the
+// desired resources with the given names
+func setDeletionPolicy(ac *XrConfig, override *v1beta1.NodegroupOverride, names ...string) {
+	if override == nil || override.DeletionPolicy == "" {
+		return
+	}
+
+	if ac.deletionPolicies == nil {
+		ac.deletionPolicies = make(map[string]string)
+	}
+
+	for _, name := range names {
+		ac.deletionPolicies[name] = override.DeletionPolicy
+	}
+}
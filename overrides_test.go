@@ -0,0 +1,164 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/go-cmp/cmp"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+func TestNodegroupOverrides(t *testing.T) {
+	overrides, warnings := newNodegroupOverrides(map[string]v1beta1.NodegroupOverride{
+		"ng-1": {
+			DeletionPolicy: "Orphan",
+			Labels:         map[string]string{"team": "platform", "not a key": "x", "owner": "not a value!"},
+		},
+		"ng-typo": {DeletionPolicy: "Delete"},
+	})
+
+	if len(warnings) != 2 {
+		t.Errorf("newNodegroupOverrides(...): want 2 warnings for invalid labels, got %v", warnings)
+	}
+
+	var (
+		cluster string   = "test"
+		ac      XrConfig = XrConfig{
+			cluster:          &cluster,
+			policy:           &metadataPolicy{},
+			additionalLabels: map[string]string{"team": "apps"},
+			overrides:        overrides,
+		}
+	)
+
+	var want map[string]string = map[string]string{
+		"team":           "platform",
+		clusterNameLabel: "test",
+		clusterLabel:     "test",
+		machinePoolLabel: "ng-1",
+	}
+	if diff := cmp.Diff(want, newLabels(&ac, "MachinePool", "ng-1")); diff != "" {
+		t.Errorf("newLabels(...): -want, +got:\n%s", diff)
+	}
+
+	setDeletionPolicy(&ac, overrides.get("ng-1"), "test-machinepool-ng-1")
+	setDeletionPolicy(&ac, overrides.get("ng-2"), "test-machinepool-ng-2")
+	if diff := cmp.Diff(map[string]string{"test-machinepool-ng-1": "Orphan"}, ac.deletionPolicies); diff != "" {
+		t.Errorf("setDeletionPolicy(...): -want, +got:\n%s", diff)
+	}
+
+	unmatched := overrides.unmatched()
+	if len(unmatched) != 1 || unmatched[0].Error() != `nodegroup override "ng-typo" matches no imported nodegroup` {
+		t.Errorf("unmatched(): want a warning for ng-typo only, got %v", unmatched)
+	}
+}
+
+func TestApplyLaunchTemplateOverride(t *testing.T) {
+	lookup := infrav2.AmazonLinux
+	cases := map[string]struct {
+		override        *v1beta1.NodegroupOverride
+		template        *expinfrav2.AWSLaunchTemplate
+		want            *expinfrav2.AWSLaunchTemplate
+		wantAnnotations map[string]string
+		wantWarn        bool
+	}{
+		"no override": {
+			template:        &expinfrav2.AWSLaunchTemplate{IamInstanceProfile: "nodes"},
+			want:            &expinfrav2.AWSLaunchTemplate{IamInstanceProfile: "nodes"},
+			wantAnnotations: map[string]string{amiIDAnnotation: "ami-0123456789abcdef0"},
+		},
+		"instance profile and AMI": {
+			override: &v1beta1.NodegroupOverride{InstanceProfile: "forced", AMIID: "ami-0fedcba9876543210"},
+			template: &expinfrav2.AWSLaunchTemplate{
+				IamInstanceProfile: "nodes",
+				AMI:                infrav2.AMIReference{EKSOptimizedLookupType: &lookup},
+			},
+			want: &expinfrav2.AWSLaunchTemplate{
+				IamInstanceProfile: "forced",
+				AMI:                infrav2.AMIReference{ID: aws.String("ami-0fedcba9876543210")},
+			},
+			wantAnnotations: map[string]string{amiPinnedAnnotation: "true"},
+		},
+		"pool without a launch template": {
+			override:        &v1beta1.NodegroupOverride{AMIID: "ami-0fedcba9876543210"},
+			wantAnnotations: map[string]string{amiIDAnnotation: "ami-0123456789abcdef0"},
+			wantWarn:        true,
+		},
+		"deletion policy only": {
+			override:        &v1beta1.NodegroupOverride{DeletionPolicy: "Orphan"},
+			wantAnnotations: map[string]string{amiIDAnnotation: "ami-0123456789abcdef0"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var annotations map[string]string = map[string]string{amiIDAnnotation: "ami-0123456789abcdef0"}
+			warning := applyLaunchTemplateOverride(tc.override, tc.template, annotations)
+			if (warning != nil) != tc.wantWarn {
+				t.Errorf("applyLaunchTemplateOverride(...): unexpected warning state: %v", warning)
+			}
+
+			if diff := cmp.Diff(tc.want, tc.template); diff != "" {
+				t.Errorf("applyLaunchTemplateOverride(...): -want, +got:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tc.wantAnnotations, annotations); diff != "" {
+				t.Errorf("applyLaunchTemplateOverride(...): annotations -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNodegroupOverrideValidation(t *testing.T) {
+	overrides, warnings := newNodegroupOverrides(map[string]v1beta1.NodegroupOverride{
+		"ng-1": {
+			DeletionPolicy:  "orphan",
+			AMIID:           "ami-not-an-id",
+			InstanceProfile: "nodes",
+		},
+		"ng-2": {
+			DeletionPolicy: "Delete",
+			AMIID:          "ami-0123456789abcdef0",
+		},
+	})
+
+	if len(warnings) != 2 {
+		t.Errorf("newNodegroupOverrides(...): want 2 warnings, got %v", warnings)
+	}
+
+	var want v1beta1.NodegroupOverride = v1beta1.NodegroupOverride{
+		InstanceProfile: "nodes",
+		Labels:          map[string]string{},
+	}
+	if diff := cmp.Diff(&want, overrides.get("ng-1")); diff != "" {
+		t.Errorf("newNodegroupOverrides(...): invalid values kept: -want, +got:\n%s", diff)
+	}
+
+	want = v1beta1.NodegroupOverride{
+		DeletionPolicy: "Delete",
+		AMIID:          "ami-0123456789abcdef0",
+		Labels:         map[string]string{},
+	}
+	if diff := cmp.Diff(&want, overrides.get("ng-2")); diff != "" {
+		t.Errorf("newNodegroupOverrides(...): valid values dropped: -want, +got:\n%s", diff)
+	}
+}
+
+func TestPinManagedAMI(t *testing.T) {
+	var (
+		al2  expinfrav2.ManagedMachineAMIType     = expinfrav2.Al2x86_64
+		spec expinfrav2.AWSManagedMachinePoolSpec = expinfrav2.AWSManagedMachinePoolSpec{
+			AMIType:           &al2,
+			AMIVersion:        aws.String("1.28.5-20240202"),
+			AWSLaunchTemplate: &expinfrav2.AWSLaunchTemplate{},
+		}
+	)
+
+	pinManagedAMI(&v1beta1.NodegroupOverride{AMIID: "ami-0123456789abcdef0"}, &spec)
+	if aws.ToString((*string)(spec.AMIType)) != "CUSTOM" || spec.AMIVersion != nil {
+		t.Errorf("pinManagedAMI(...): want CUSTOM AMI type without a release version, got %v, %v", spec.AMIType, spec.AMIVersion)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+// objectPatch holds a patch given in the input, parsed once for every object
+// it is applied to
+type objectPatch struct {
+	index      int
+	kind, name string
+	patchType  v1beta1.PatchType
+	operations jsonpatch.Patch
+	merge      map[string]any
+}
+
+// newPatches parses the patches given in the input. Patches which cannot be
+// parsed are left out and returned as warnings.
+func newPatches(spec []v1beta1.Patch) (patches []objectPatch, warnings []error) {
+	for i, p := range spec {
+		patch, err := newPatch(i, p)
+		if err != nil {
+			warnings = append(warnings, errors.Wrapf(err, "patch %d is ignored", i))
+			continue
+		}
+		patches = append(patches, patch)
+	}
+	return
+}
+
+func newPatch(index int, spec v1beta1.Patch) (patch objectPatch, err error) {
+	patch = objectPatch{
+		index:     index,
+		kind:      spec.Target.Kind,
+		name:      spec.Target.Name,
+		patchType: spec.Type,
+	}
+
+	if patch.name == "" {
+		patch.name = "*"
+	}
+
+	if _, err = path.Match(patch.name, ""); err != nil {
+		return patch, errors.Wrapf(err, "invalid name glob %q", patch.name)
+	}
+
+	var data []byte
+	if data, err = yaml.YAMLToJSON([]byte(spec.Patch)); err != nil {
+		return patch, errors.Wrap(err, "cannot parse patch")
+	}
+
+	switch patch.patchType {
+	case v1beta1.PatchTypeJSONPatch:
+		if patch.operations, err = jsonpatch.DecodePatch(data); err != nil {
+			return patch, errors.Wrap(err, "invalid JSON patch")
+		}
+	case v1beta1.PatchTypeStrategicMerge, "":
+		patch.patchType = v1beta1.PatchTypeStrategicMerge
+		if err = json.Unmarshal(data, &patch.merge); err != nil || patch.merge == nil {
+			return patch, errors.New("strategic merge patch must be an object")
+		}
+	default:
+		return patch, fmt.Errorf("unknown patch type %q", patch.patchType)
+	}
+	return
+}
+
+// matches returns true if the patch targets the object of the given kind
+// and name
+func (p *objectPatch) matches(kind, name string) bool {
+	if !strings.EqualFold(p.kind, kind) {
+		return false
+	}
+
+	matched, _ := path.Match(p.name, name)
+	return matched
+}
+
+// apply returns a patched copy of the manifest. The manifest itself is never
+// changed, so a failed patch leaves it as it was.
+//
+// Strategic merge patches take the patch strategy from the Go type of the
+// object the manifest was built from. Lists merge by key only where that type
+// declares a merge key, such as the requirements of a NodePool. Every other
+// list, which includes most CAPA fields, is replaced by the one in the patch.
+func (p *objectPatch) apply(object any, manifest map[string]any) (patched map[string]any, err error) {
+	switch p.patchType {
+	case v1beta1.PatchTypeJSONPatch:
+		var data []byte
+		if data, err = json.Marshal(manifest); err != nil {
+			return
+		}
+
+		if data, err = p.operations.Apply(data); err != nil {
+			return
+		}
+
+		err = json.Unmarshal(data, &patched)
+	default:
+		patched, err = strategicpatch.StrategicMergeMapPatch(runtime.DeepCopyJSON(manifest), p.merge, object)
+	}
+	return
+}
+
+// applyPatches applies every patch targeting the object in order. Patches
+// which fail are skipped and reported as warnings.
+func applyPatches(ac *XrConfig, object any, manifest map[string]any) map[string]any {
+	var (
+		kind, _ = manifest["kind"].(string)
+		name    string
+	)
+
+	if metadata, ok := manifest["metadata"].(map[string]any); ok {
+		name, _ = metadata["name"].(string)
+	}
+
+	for _, p := range ac.patches {
+		if !p.matches(kind, name) {
+			continue
+		}
+
+		patched, err := p.apply(object, manifest)
+		if err != nil {
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "patch %d cannot be applied to %s %q", p.index, kind, name))
+			continue
+		}
+		manifest = patched
+	}
+	return manifest
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+func TestApplyPatches(t *testing.T) {
+	newManifest := func() map[string]any {
+		return map[string]any{
+			"kind": "AWSManagedMachinePool",
+			"metadata": map[string]any{
+				"name":   "test-awsmanagedmachinepool-ng-1",
+				"labels": map[string]any{"team": "platform"},
+			},
+			"spec": map[string]any{
+				"eksNodegroupName": "ng-1",
+				"capacityType":     "onDemand",
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		patches      []v1beta1.Patch
+		want         map[string]any
+		wantWarnings int
+	}{
+		"strategic merge patch": {
+			patches: []v1beta1.Patch{{
+				Target: v1beta1.PatchTarget{Kind: "awsmanagedmachinepool", Name: "test-*"},
+				Patch:  "metadata:\n  labels:\n    site: dc1\nspec:\n  capacityType: spot\n",
+			}},
+			want: map[string]any{
+				"kind": "AWSManagedMachinePool",
+				"metadata": map[string]any{
+					"name":   "test-awsmanagedmachinepool-ng-1",
+					"labels": map[string]any{"team": "platform", "site": "dc1"},
+				},
+				"spec": map[string]any{
+					"eksNodegroupName": "ng-1",
+					"capacityType":     "spot",
+				},
+			},
+		},
+		"JSON patches apply in order": {
+			patches: []v1beta1.Patch{
+				{
+					Target: v1beta1.PatchTarget{Kind: "AWSManagedMachinePool"},
+					Type:   v1beta1.PatchTypeJSONPatch,
+					Patch:  `[{"op":"remove","path":"/metadata/labels/team"}]`,
+				},
+				{
+					Target: v1beta1.PatchTarget{Kind: "AWSManagedMachinePool"},
+					Type:   v1beta1.PatchTypeJSONPatch,
+					Patch:  "- op: add\n  path: /metadata/labels/team\n  value: apps\n",
+				},
+			},
+			want: map[string]any{
+				"kind": "AWSManagedMachinePool",
+				"metadata": map[string]any{
+					"name":   "test-awsmanagedmachinepool-ng-1",
+					"labels": map[string]any{"team": "apps"},
+				},
+				"spec": map[string]any{
+					"eksNodegroupName": "ng-1",
+					"capacityType":     "onDemand",
+				},
+			},
+		},
+		"other kinds and names are left alone": {
+			patches: []v1beta1.Patch{
+				{Target: v1beta1.PatchTarget{Kind: "MachinePool"}, Patch: "spec:\n  replicas: 1\n"},
+				{Target: v1beta1.PatchTarget{Kind: "AWSManagedMachinePool", Name: "*-ng-2"}, Patch: "spec:\n  capacityType: spot\n"},
+			},
+			want: newManifest(),
+		},
+		"failed patches are warnings": {
+			patches: []v1beta1.Patch{
+				{
+					Target: v1beta1.PatchTarget{Kind: "AWSManagedMachinePool"},
+					Type:   v1beta1.PatchTypeJSONPatch,
+					Patch:  `[{"op":"replace","path":"/spec/missing","value":1}]`,
+				},
+				{
+					Target: v1beta1.PatchTarget{Kind: "AWSManagedMachinePool"},
+					Type:   v1beta1.PatchTypeJSONPatch,
+					Patch:  `{"op":"add"}`,
+				},
+				{
+					Target: v1beta1.PatchTarget{Kind: "AWSManagedMachinePool", Name: "[-"},
+					Patch:  "spec: {}",
+				},
+			},
+			want:         newManifest(),
+			wantWarnings: 3,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var (
+				ac       XrConfig
+				warnings []error
+			)
+
+			ac.patches, warnings = newPatches(tc.patches)
+			ac.warnings = append(ac.warnings, warnings...)
+
+			var manifest map[string]any = newManifest()
+			got := applyPatches(&ac, expinfrav2.AWSManagedMachinePool{}, manifest)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("applyPatches(...): -want, +got:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(newManifest(), manifest); diff != "" {
+				t.Errorf("applyPatches(...): manifest was modified: -want, +got:\n%s", diff)
+			}
+
+			if len(ac.warnings) != tc.wantWarnings {
+				t.Errorf("applyPatches(...): want %d warnings, got %v", tc.wantWarnings, ac.warnings)
+			}
+		})
+	}
+}
+
+func TestApplyPatchesNodePoolRequirements(t *testing.T) {
+	requirement := func(key string, values ...any) map[string]any {
+		return map[string]any{"key": key, "operator": "In", "values": values}
+	}
+
+	manifest := map[string]any{
+		"kind":     "NodePool",
+		"metadata": map[string]any{"name": "test-nodepool-ng-1"},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"requirements": []any{
+						requirement("karpenter.sh/capacity-type", "on-demand"),
+						requirement("node.kubernetes.io/instance-type", "m5.large"),
+						requirement("topology.kubernetes.io/zone", "eu-central-1a", "eu-central-1b"),
+					},
+				},
+			},
+		},
+	}
+
+	var (
+		ac       XrConfig
+		warnings []error
+	)
+	ac.patches, warnings = newPatches([]v1beta1.Patch{{
+		Target: v1beta1.PatchTarget{Kind: "NodePool"},
+		Patch: `spec:
+  template:
+    spec:
+      requirements:
+      - key: node.kubernetes.io/instance-type
+        values: [m5.large, m5.xlarge]
+`,
+	}})
+	ac.warnings = append(ac.warnings, warnings...)
+
+	want := []any{
+		requirement("karpenter.sh/capacity-type", "on-demand"),
+		requirement("node.kubernetes.io/instance-type", "m5.large", "m5.xlarge"),
+		requirement("topology.kubernetes.io/zone", "eu-central-1a", "eu-central-1b"),
+	}
+
+	got := applyPatches(&ac, &NodePool{}, manifest)
+	requirements, _, _ := unstructured.NestedSlice(got, "spec", "template", "spec", "requirements")
+	if diff := cmp.Diff(want, requirements); diff != "" {
+		t.Errorf("applyPatches(...): requirements -want, +got:\n%s", diff)
+	}
+
+	if len(ac.warnings) != 0 {
+		t.Errorf("applyPatches(...): want no warnings, got %v", ac.warnings)
+	}
+}
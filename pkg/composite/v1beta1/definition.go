@@ -18,6 +18,10 @@ type XrObjectDefinition struct {
 
 	// Spec The specification of the XR
 	Spec XrClaimSpec `json:"spec"`
+
+	// Status The information reported back by the function
+	// +optional
+	Status XrStatus `json:"status,omitempty"`
 }
 
 type CompositeObject struct {
@@ -96,3 +100,40 @@ type MatchLabels struct {
 	// The provider label used to select the composition
 	Provider string `json:"provider"`
 }
+
+// XrStatus is the information this function writes to the XR status
+type XrStatus struct {
+	// Karpenter summarises the capacity launched by Karpenter for the cluster
+	// +optional
+	Karpenter *KarpenterStatus `json:"karpenter,omitempty"`
+}
+
+// KarpenterStatus summarises the instances launched by Karpenter
+type KarpenterStatus struct {
+	// Instances is the total number of instances launched by Karpenter
+	Instances int `json:"instances"`
+
+	// NodePools breaks the instances down by the NodePool that launched them
+	// +optional
+	NodePools []KarpenterNodePool `json:"nodePools,omitempty"`
+}
+
+// KarpenterNodePool summarises the instances launched for a single NodePool
+type KarpenterNodePool struct {
+	// Name of the NodePool, taken from the `karpenter.sh/nodepool` tag or
+	// the `karpenter.sh/provisioner-name` tag on older Karpenter releases
+	Name string `json:"name"`
+
+	// Instances is the number of instances launched for the NodePool
+	Instances int `json:"instances"`
+
+	// InstanceTypes counts the instances of each instance type
+	// +optional
+	// +mapType=granular
+	InstanceTypes map[string]int `json:"instanceTypes,omitempty"`
+
+	// Zones counts the instances in each availability zone
+	// +optional
+	// +mapType=granular
+	Zones map[string]int `json:"zones,omitempty"`
+}
@@ -10,7 +10,9 @@ import (
 )
 
 // This isn't a custom resource, in the sense that we never install its CRD.
-// It is a KRM-like object, so we generate a CRD to describe its schema.
+// It is a KRM-like object, so we generate a CRD to describe its schema. As
+// nothing enforces the validation markers below, the function repeats those
+// checks when it reads the input.
 
 // Input can be used to provide input to this Function.
 // +kubebuilder:object:root=true
@@ -29,4 +31,336 @@ type Spec struct {
 	// ClusterRef The XR name of the cluster resource that will be created.
 	// This is not the same as `clusterName`.
 	ClusterRef resource.Name `json:"clusterRef"`
+
+	// Tags Controls which AWS tags are imported into `additionalTags`.
+	// Tags managed by AWS (`aws:*`, `eks:*` and `kubernetes.io/cluster/*`)
+	// are never imported.
+	// +optional
+	Tags *TagFilter `json:"tags,omitempty"`
+
+	// ImportRolePolicies When true, the managed policies attached to the node
+	// role are looked up in IAM and added to `roleAdditionalPolicies`.
+	// +optional
+	ImportRolePolicies bool `json:"importRolePolicies,omitempty"`
+
+	// SelfManaged When true, autoscaling groups tagged
+	// `kubernetes.io/cluster/<name>=owned` which do not belong to an EKS
+	// managed nodegroup are imported as `AWSMachinePool` objects.
+	// +optional
+	SelfManaged bool `json:"selfManaged,omitempty"`
+
+	// ImportControlPlane When true, the EKS cluster is described and imported
+	// as a `Cluster`, `AWSManagedControlPlane` and `AWSManagedCluster`.
+	// +optional
+	ImportControlPlane bool `json:"importControlPlane,omitempty"`
+
+	// ImportFargateProfiles When true, the fargate profiles of the cluster
+	// are imported as `AWSFargateProfile`.
+	// +optional
+	ImportFargateProfiles bool `json:"importFargateProfiles,omitempty"`
+
+	// DiscoverKarpenter When true, the instances Karpenter launched for the
+	// cluster are looked up and summarised in `status.karpenter` on the XR.
+	// +optional
+	DiscoverKarpenter bool `json:"discoverKarpenter,omitempty"`
+
+	// GenerateKarpenter When true, a Karpenter `NodePool` and `EC2NodeClass`
+	// are generated for each nodegroup to help migrating to Karpenter.
+	// +optional
+	GenerateKarpenter bool `json:"generateKarpenter,omitempty"`
+
+	// ClusterAutoscaler When true, MachinePools are annotated with the size
+	// bounds read by the cluster-autoscaler CAPI provider, and a ConfigMap
+	// lists the bounds of each autoscaling group for the AWS cloud provider.
+	// +optional
+	ClusterAutoscaler bool `json:"clusterAutoscaler,omitempty"`
+
+	// Propagation Controls which labels and annotations of the XR are copied
+	// onto each kind of generated object.
+	// +optional
+	Propagation *PropagationPolicy `json:"propagation,omitempty"`
+
+	// NameTemplate A Go template for the names of generated objects, given
+	// `.Cluster`, the lower case `.Kind` and the pool or profile `.Name`,
+	// which is empty for objects generated once per cluster. The CAPI
+	// Cluster has the kind `cluster`. Names are lower cased, underscores are
+	// replaced with dashes and names longer than 63 characters are shortened
+	// with a hash suffix.
+	// +optional
+	NameTemplate string `json:"nameTemplate,omitempty"`
+
+	// FieldPaths Where the values the function needs are read from. Values
+	// without a path are read from their place on the XR of this function.
+	// +optional
+	FieldPaths *FieldPaths `json:"fieldPaths,omitempty"`
+
+	// UseObservedCluster When true, the cluster name is read from the
+	// `crossplane.io/external-name` annotation of the observed resource named
+	// by `clusterRef`, and the region from its `spec.forProvider.region`,
+	// unless field paths are given for them.
+	// +optional
+	UseObservedCluster bool `json:"useObservedCluster,omitempty"`
+
+	// TargetNamespace The namespace generated objects are created in. When
+	// empty the namespace of the claim is used, and for XRs created without a
+	// claim the default namespace the function was started with.
+	// +optional
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// Provider The cloud the cluster runs on. When empty the provider label
+	// of the composition selector is used, and without one the cloud is
+	// inferred from the API group of the observed resource named by
+	// `clusterRef`.
+	// +optional
+	// +kubebuilder:validation:Enum=aws;azure;gcp
+	Provider string `json:"provider,omitempty"`
+
+	// Nodegroups Selects the EKS managed nodegroups, self-managed autoscaling
+	// groups and fargate profiles which are imported. When empty all of them
+	// are imported.
+	// +optional
+	Nodegroups *NodegroupFilter `json:"nodegroups,omitempty"`
+
+	// NodegroupOverrides Changes made to the objects generated for a single
+	// pool, keyed by the name of the nodegroup or self-managed autoscaling
+	// group. Overrides are applied after the pool has been mapped.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self.all(k, size(k) > 0)",message="nodegroup names must not be empty"
+	NodegroupOverrides map[string]NodegroupOverride `json:"nodegroupOverrides,omitempty"`
+
+	// Patches Changes made to generated objects before they are wrapped for
+	// provider-kubernetes, applied in order. Patches which cannot be parsed
+	// or applied are reported as warnings.
+	// +optional
+	Patches []Patch `json:"patches,omitempty"`
+}
+
+// TagFilter - Defines the patterns used to select AWS tags by their key
+type TagFilter struct {
+	// Include Regular expressions a tag key must match at least one of to be
+	// imported. When empty all tags are imported.
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude Regular expressions for tag keys that must not be imported.
+	// Exclusions take precedence over inclusions.
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// PropagationPolicy - Defines which labels and annotations of the XR flow to
+// which kinds of generated object
+type PropagationPolicy struct {
+	// Labels Rules for the labels of the XR. Kinds no rule applies to are
+	// given every label.
+	// +optional
+	Labels []PropagationRule `json:"labels,omitempty"`
+
+	// Annotations Rules for the annotations of the XR. Kinds no rule applies
+	// to are given no annotations.
+	// +optional
+	Annotations []PropagationRule `json:"annotations,omitempty"`
+}
+
+// PropagationRule - Defines the keys propagated to a set of generated kinds
+type PropagationRule struct {
+	// Kinds The kinds of generated object the rule applies to, such as
+	// `MachinePool`. When empty the rule applies to every kind.
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Allow Regular expressions a key must match at least one of to be
+	// propagated. When empty all keys are allowed.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny Regular expressions for keys that must not be propagated.
+	// Denials take precedence over allows.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+}
+
+// FieldSource - The object a value is read from
+// +kubebuilder:validation:Enum=Composite;ObservedCluster;Environment
+type FieldSource string
+
+const (
+	// FieldSourceComposite reads the value from the XR
+	FieldSourceComposite FieldSource = "Composite"
+
+	// FieldSourceObservedCluster reads the value from the observed resource
+	// named by `clusterRef`
+	FieldSourceObservedCluster FieldSource = "ObservedCluster"
+
+	// FieldSourceEnvironment reads the value from the environment config
+	FieldSourceEnvironment FieldSource = "Environment"
+)
+
+// FieldRef - Defines where a single value is read from
+type FieldRef struct {
+	// Source The object the value is read from
+	// +optional
+	// +kubebuilder:default=Composite
+	Source FieldSource `json:"source,omitempty"`
+
+	// Path The field path of the value on the source, such as
+	// `spec.clusterName`
+	Path string `json:"path"`
+}
+
+// FieldPaths - Defines where each value the function needs is read from
+type FieldPaths struct {
+	// ClusterName The name of the EKS cluster.
+	// Defaults to `spec.clusterName` on the XR.
+	// +optional
+	ClusterName *FieldRef `json:"clusterName,omitempty"`
+
+	// Namespace The namespace generated objects are created in.
+	// Defaults to `spec.claimRef.namespace` on the XR.
+	// +optional
+	Namespace *FieldRef `json:"namespace,omitempty"`
+
+	// Region The AWS region of the cluster.
+	// Defaults to `spec.regionOrLocation` on the XR.
+	// +optional
+	Region *FieldRef `json:"region,omitempty"`
+
+	// CloudProviderConfigRef The providerconfig used to reach AWS.
+	// Defaults to `spec.cloudProviderConfigRef` on the XR.
+	// +optional
+	CloudProviderConfigRef *FieldRef `json:"cloudProviderConfigRef,omitempty"`
+
+	// ClusterProviderConfigRef The providerconfig of provider-kubernetes.
+	// Defaults to `spec.clusterProviderConfigRef` on the XR.
+	// +optional
+	ClusterProviderConfigRef *FieldRef `json:"clusterProviderConfigRef,omitempty"`
+
+	// ObjectDeletionPolicy The deletion policy of generated objects.
+	// Defaults to `spec.objectDeletionPolicy` on the XR.
+	// +optional
+	ObjectDeletionPolicy *FieldRef `json:"objectDeletionPolicy,omitempty"`
+
+	// AdditionalLabels A map of labels added to every generated object.
+	// Defaults to `spec.kubernetesAdditionalLabels` on the XR.
+	// +optional
+	AdditionalLabels *FieldRef `json:"additionalLabels,omitempty"`
+
+	// Provider The cloud provider of the cluster.
+	// Defaults to `spec.compositionSelector.matchLabels.provider` on the XR.
+	// +optional
+	Provider *FieldRef `json:"provider,omitempty"`
+}
+
+// NodegroupFilter - Defines which nodegroups are imported
+// +kubebuilder:validation:XValidation:rule="has(self.include) || has(self.exclude)",message="at least one of include or exclude must be set"
+type NodegroupFilter struct {
+	// Include Selectors a nodegroup must match at least one of to be
+	// imported. When empty all nodegroups are imported.
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Include []NodegroupSelector `json:"include,omitempty"`
+
+	// Exclude Selectors for nodegroups that must not be imported.
+	// Exclusions take precedence over inclusions.
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Exclude []NodegroupSelector `json:"exclude,omitempty"`
+}
+
+// NodegroupSelector - Matches nodegroups. A nodegroup must match every field
+// set on the selector.
+// +kubebuilder:validation:XValidation:rule="has(self.name) || has(self.labels) || has(self.tags) || has(self.capacityType)",message="at least one of name, labels, tags or capacityType must be set"
+type NodegroupSelector struct {
+	// Name A regular expression the nodegroup name must match
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=256
+	Name string `json:"name,omitempty"`
+
+	// Labels A selector for the Kubernetes labels of the nodegroup
+	// +optional
+	Labels *metav1.LabelSelector `json:"labels,omitempty"`
+
+	// Tags AWS tags the nodegroup must carry. An empty value only requires
+	// the tag to be present.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=50
+	// +kubebuilder:validation:XValidation:rule="self.all(k, size(k) > 0 && size(k) <= 128 && size(self[k]) <= 256)",message="tag keys must be 1 to 128 and values at most 256 characters long"
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// CapacityType The capacity type of the nodegroup
+	// +optional
+	// +kubebuilder:validation:Enum=ON_DEMAND;SPOT;CAPACITY_BLOCK
+	CapacityType string `json:"capacityType,omitempty"`
+}
+
+// NodegroupOverride - Defines the changes made to the objects generated for a
+// pool
+// +kubebuilder:validation:XValidation:rule="has(self.deletionPolicy) || has(self.labels) || has(self.instanceProfile) || has(self.amiID)",message="at least one of deletionPolicy, labels, instanceProfile or amiID must be set"
+type NodegroupOverride struct {
+	// DeletionPolicy The deletion policy of the objects generated for the
+	// pool, replacing the deletion policy read from the XR
+	// +optional
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// Labels Labels added to every object generated for the pool. They take
+	// precedence over labels propagated from the XR and additional labels.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=64
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// InstanceProfile The IAM instance profile set on the launch template of
+	// the pool. Pools without a launch template cannot be given one.
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=128
+	InstanceProfile string `json:"instanceProfile,omitempty"`
+
+	// AMIID The ID of the AMI the launch template of the pool is pinned to.
+	// Pools without a launch template cannot be pinned.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^ami-[0-9a-f]{8}([0-9a-f]{9})?$`
+	AMIID string `json:"amiID,omitempty"`
+}
+
+// PatchType - The format of a patch
+// +kubebuilder:validation:Enum=JSONPatch;StrategicMerge
+type PatchType string
+
+const (
+	// PatchTypeJSONPatch is a list of RFC 6902 operations
+	PatchTypeJSONPatch PatchType = "JSONPatch"
+
+	// PatchTypeStrategicMerge is a partial object merged using the patch
+	// strategy of the generated type
+	PatchTypeStrategicMerge PatchType = "StrategicMerge"
+)
+
+// Patch - Defines a patch applied to generated objects
+type Patch struct {
+	// Target The generated objects the patch applies to
+	Target PatchTarget `json:"target"`
+
+	// Type The format of the patch
+	// +optional
+	// +kubebuilder:default=StrategicMerge
+	Type PatchType `json:"type,omitempty"`
+
+	// Patch The patch as YAML or JSON. A list of operations for `JSONPatch`
+	// and a partial object for `StrategicMerge`.
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+}
+
+// PatchTarget - Selects generated objects by kind and name
+type PatchTarget struct {
+	// Kind The kind of the objects, such as `AWSManagedMachinePool`
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name A glob matched against the name of the objects. When empty every
+	// object of the kind is patched.
+	// +optional
+	Name string `json:"name,omitempty"`
 }
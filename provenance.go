@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/function-sdk-go/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// sourceARNAnnotation holds the ARN of the AWS resource an object was
+	// imported from
+	sourceARNAnnotation = "describenodegroups.fn.giantswarm.io/source-arn"
+
+	// autoscalingGroupsAnnotation holds the comma separated names of the
+	// autoscaling groups behind an object
+	autoscalingGroupsAnnotation = "describenodegroups.fn.giantswarm.io/autoscaling-groups"
+
+	// launchTemplateIDAnnotation holds the ID of the launch template used by
+	// the source
+	launchTemplateIDAnnotation = "describenodegroups.fn.giantswarm.io/launch-template-id"
+
+	// launchTemplateVersionAnnotation holds the launch template version used
+	// by the source
+	launchTemplateVersionAnnotation = "describenodegroups.fn.giantswarm.io/launch-template-version"
+
+	// accountAnnotation holds the AWS account the source lives in
+	accountAnnotation = "describenodegroups.fn.giantswarm.io/account"
+
+	// regionAnnotation holds the AWS region the source lives in
+	regionAnnotation = "describenodegroups.fn.giantswarm.io/region"
+
+	// importedAtAnnotation holds the time the imported content last changed
+	importedAtAnnotation = "describenodegroups.fn.giantswarm.io/imported-at"
+
+	// sourceHashAnnotation holds a hash of the AWS API output an object was
+	// mapped from
+	sourceHashAnnotation = "describenodegroups.fn.giantswarm.io/source-hash"
+)
+
+// now returns the time used for the import timestamp. Tests replace it to get
+// stable output.
+var now = time.Now
+
+// provenance describes the AWS resources a generated object was imported from
+type provenance struct {
+	arn                   string
+	autoscalingGroups     []string
+	launchTemplateID      string
+	launchTemplateVersion string
+
+	// hash is the sourceHash of the AWS API output the object was mapped from
+	hash string
+}
+
+// sourceHash returns the sha256 of the AWS API output an object was mapped
+// from. It is empty if the output cannot be encoded.
+//
+// Hashing the source rather than the generated manifest keeps the hash stable
+// when only the mapping or the XR changes.
+func sourceHash(sources ...any) string {
+	b, err := json.Marshal(sources)
+	if err != nil {
+		return ""
+	}
+
+	var sum [32]byte = sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// The source functions below return copies of the AWS API output without the
+// fields which change as the resource scales or its health changes, so that
+// the hash only moves when its configuration does
+
+// asgSource returns the autoscaling group without its instances, desired
+// capacity, status and suspended processes
+func asgSource(group *asgtypes.AutoScalingGroup) *asgtypes.AutoScalingGroup {
+	if group == nil {
+		return nil
+	}
+
+	var source asgtypes.AutoScalingGroup = *group
+	source.Instances = nil
+	source.DesiredCapacity = nil
+	source.Status = nil
+	source.SuspendedProcesses = nil
+	return &source
+}
+
+// nodegroupSource returns the nodegroup without its desired size, status,
+// health and modification time
+func nodegroupSource(group *types.Nodegroup) *types.Nodegroup {
+	if group == nil {
+		return nil
+	}
+
+	var source types.Nodegroup = *group
+	if group.ScalingConfig != nil {
+		var scaling types.NodegroupScalingConfig = *group.ScalingConfig
+		scaling.DesiredSize = nil
+		source.ScalingConfig = &scaling
+	}
+	source.Status = ""
+	source.Health = nil
+	source.ModifiedAt = nil
+	return &source
+}
+
+// fargateProfileSource returns the fargate profile without its status
+func fargateProfileSource(profile *types.FargateProfile) *types.FargateProfile {
+	if profile == nil {
+		return nil
+	}
+
+	var source types.FargateProfile = *profile
+	source.Status = ""
+	source.Health = nil
+	return &source
+}
+
+// clusterSource returns the cluster without its status and health
+func clusterSource(cluster *types.Cluster) *types.Cluster {
+	if cluster == nil {
+		return nil
+	}
+
+	var source types.Cluster = *cluster
+	source.Status = ""
+	source.Health = nil
+	return &source
+}
+
+// launchTemplateSource returns the launch template version data, if the
+// launch template could be read
+func launchTemplateSource(config *LaunchTemplateConfig) *ec2types.ResponseLaunchTemplateData {
+	if config == nil {
+		return nil
+	}
+	return config.data
+}
+
+// nodegroupProvenance describes the EKS managed nodegroup and the resources
+// EKS created for it
+func nodegroupProvenance(group *types.Nodegroup, ng *NodegroupConfig) (source provenance) {
+	source.arn = aws.ToString(group.NodegroupArn)
+	source.hash = sourceHash(ng.sources...)
+	if group.LaunchTemplate != nil {
+		source.launchTemplateID = aws.ToString(group.LaunchTemplate.Id)
+		source.launchTemplateVersion = aws.ToString(group.LaunchTemplate.Version)
+	}
+
+	if group.Resources != nil {
+		for _, asg := range group.Resources.AutoScalingGroups {
+			source.autoscalingGroups = append(source.autoscalingGroups, aws.ToString(asg.Name))
+		}
+	}
+	return
+}
+
+// autoscalingGroupProvenance describes a self-managed autoscaling group and
+// the launch template found for it
+func autoscalingGroupProvenance(group *asgtypes.AutoScalingGroup, config *AutoscalingGroupConfig) provenance {
+	return provenance{
+		arn:                   aws.ToString(group.AutoScalingGroupARN),
+		autoscalingGroups:     []string{aws.ToString(group.AutoScalingGroupName)},
+		launchTemplateID:      config.launchTemplateID,
+		launchTemplateVersion: aws.ToString(config.launchTemplateVersion),
+		hash:                  sourceHash(config.sources...),
+	}
+}
+
+// setProvenance adds the provenance annotations to the manifest of a
+// generated object
+//
+// While the hash of the source is unchanged, the import timestamp is carried
+// over from the observed object so that it records when the AWS resources
+// last changed rather than when the function last ran.
+func setProvenance(ac *XrConfig, name string, manifest map[string]any, source provenance) (err error) {
+	var annotations map[string]string = make(map[string]string)
+	if existing, ok, _ := unstructured.NestedStringMap(manifest, "metadata", "annotations"); ok {
+		annotations = existing
+	}
+
+	if source.arn != "" {
+		annotations[sourceARNAnnotation] = source.arn
+		if parsed, err := arn.Parse(source.arn); err == nil && parsed.AccountID != "" {
+			annotations[accountAnnotation] = parsed.AccountID
+		}
+	}
+
+	if len(source.autoscalingGroups) > 0 {
+		annotations[autoscalingGroupsAnnotation] = strings.Join(source.autoscalingGroups, ",")
+	}
+
+	if source.launchTemplateID != "" {
+		annotations[launchTemplateIDAnnotation] = source.launchTemplateID
+	}
+
+	if source.launchTemplateVersion != "" {
+		annotations[launchTemplateVersionAnnotation] = source.launchTemplateVersion
+	}
+
+	if *ac.region != "" {
+		annotations[regionAnnotation] = *ac.region
+	}
+
+	annotations[importedAtAnnotation] = now().UTC().Format(time.RFC3339)
+	if source.hash != "" {
+		annotations[sourceHashAnnotation] = source.hash
+		if observed, ok := ac.composed.ObservedComposed[resource.Name(name)]; ok && observed.Resource != nil {
+			previous, _, _ := unstructured.NestedStringMap(observed.Resource.Object, "spec", "forProvider", "manifest", "metadata", "annotations")
+			if previous[sourceHashAnnotation] == source.hash && previous[importedAtAnnotation] != "" {
+				annotations[importedAtAnnotation] = previous[importedAtAnnotation]
+			}
+		}
+	}
+
+	return unstructured.SetNestedStringMap(manifest, annotations, "metadata", "annotations")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/giantswarm/xfnlib/pkg/composite"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSetProvenance(t *testing.T) {
+	var restore func() time.Time = now
+	t.Cleanup(func() { now = restore })
+	now = func() time.Time {
+		return time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	newSource := func(desired int32) provenance {
+		return provenance{
+			arn:                   "arn:aws:eks:eu-central-1:123456789012:nodegroup/test/ng-1/abcd",
+			autoscalingGroups:     []string{"eks-ng-1-abcd"},
+			launchTemplateID:      "lt-123456",
+			launchTemplateVersion: "2",
+			hash: sourceHash(&types.Nodegroup{
+				NodegroupName: aws.String("ng-1"),
+				ScalingConfig: &types.NodegroupScalingConfig{DesiredSize: aws.Int32(desired)},
+			}),
+		}
+	}
+
+	newManifest := func(replicas int64) map[string]any {
+		return map[string]any{
+			"kind": "MachinePool",
+			"metadata": map[string]any{
+				"name":        "test-machinepool-ng-1",
+				"annotations": map[string]any{"example.com/team": "platform"},
+			},
+			"spec":   map[string]any{"replicas": replicas},
+			"status": map[string]any{"replicas": int64(0)},
+		}
+	}
+
+	observed := func(hash, importedAt string) map[resource.Name]resource.ObservedComposed {
+		var u *composed.Unstructured = composed.New()
+		_ = unstructured.SetNestedStringMap(u.Object, map[string]string{
+			sourceHashAnnotation: hash,
+			importedAtAnnotation: importedAt,
+		}, "spec", "forProvider", "manifest", "metadata", "annotations")
+		return map[resource.Name]resource.ObservedComposed{
+			"test-machinepool-ng-1": {Resource: u},
+		}
+	}
+
+	cases := map[string]struct {
+		source     provenance
+		replicas   int64
+		observed   map[resource.Name]resource.ObservedComposed
+		importedAt string
+	}{
+		"new object is stamped with the current time": {
+			source:     newSource(3),
+			replicas:   3,
+			importedAt: "2024-03-01T12:00:00Z",
+		},
+		"unchanged source keeps its import time": {
+			source:     newSource(3),
+			replicas:   3,
+			observed:   observed(newSource(3).hash, "2023-12-24T08:00:00Z"),
+			importedAt: "2023-12-24T08:00:00Z",
+		},
+		"changed manifest of an unchanged source keeps its import time": {
+			source:     newSource(3),
+			replicas:   5,
+			observed:   observed(newSource(3).hash, "2023-12-24T08:00:00Z"),
+			importedAt: "2023-12-24T08:00:00Z",
+		},
+		"changed source is stamped with the current time": {
+			source:     newSource(5),
+			replicas:   5,
+			observed:   observed(newSource(3).hash, "2023-12-24T08:00:00Z"),
+			importedAt: "2024-03-01T12:00:00Z",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var (
+				region   string         = "eu-central-1"
+				manifest map[string]any = newManifest(tc.replicas)
+				ac       XrConfig       = XrConfig{
+					region:   &region,
+					composed: &composite.Composition{ObservedComposed: tc.observed},
+				}
+			)
+
+			if err := setProvenance(&ac, "test-machinepool-ng-1", manifest, tc.source); err != nil {
+				t.Fatalf("setProvenance(...): unexpected error: %v", err)
+			}
+
+			got, _, _ := unstructured.NestedStringMap(manifest, "metadata", "annotations")
+			var want map[string]string = map[string]string{
+				"example.com/team":              "platform",
+				sourceARNAnnotation:             tc.source.arn,
+				accountAnnotation:               "123456789012",
+				regionAnnotation:                "eu-central-1",
+				autoscalingGroupsAnnotation:     "eks-ng-1-abcd",
+				launchTemplateIDAnnotation:      "lt-123456",
+				launchTemplateVersionAnnotation: "2",
+				sourceHashAnnotation:            tc.source.hash,
+				importedAtAnnotation:            tc.importedAt,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("setProvenance(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSourceHashIgnoresScaling(t *testing.T) {
+	var group *asgtypes.AutoScalingGroup = &asgtypes.AutoScalingGroup{
+		AutoScalingGroupName: aws.String("eks-ng-1-abcd"),
+		MinSize:              aws.Int32(1),
+		MaxSize:              aws.Int32(5),
+		DesiredCapacity:      aws.Int32(1),
+		Instances:            []asgtypes.Instance{{InstanceId: aws.String("i-1")}},
+	}
+
+	var scaled asgtypes.AutoScalingGroup = *group
+	scaled.DesiredCapacity = aws.Int32(3)
+	scaled.Status = aws.String("Delete in progress")
+	scaled.SuspendedProcesses = []asgtypes.SuspendedProcess{{ProcessName: aws.String("Launch")}}
+	scaled.Instances = append(scaled.Instances,
+		asgtypes.Instance{InstanceId: aws.String("i-2")},
+		asgtypes.Instance{InstanceId: aws.String("i-3")},
+	)
+
+	var resized asgtypes.AutoScalingGroup = *group
+	resized.MaxSize = aws.Int32(10)
+
+	var nodegroup *types.Nodegroup = &types.Nodegroup{
+		NodegroupName: aws.String("ng-1"),
+		Status:        types.NodegroupStatusActive,
+		ScalingConfig: &types.NodegroupScalingConfig{
+			MinSize:     aws.Int32(1),
+			MaxSize:     aws.Int32(5),
+			DesiredSize: aws.Int32(1),
+		},
+	}
+
+	var scaledNodegroup types.Nodegroup = *nodegroup
+	scaledNodegroup.Status = types.NodegroupStatusDegraded
+	scaledNodegroup.ModifiedAt = aws.Time(time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC))
+	scaledNodegroup.Health = &types.NodegroupHealth{
+		Issues: []types.Issue{{Code: types.NodegroupIssueCodeAsgInstanceLaunchFailures}},
+	}
+	scaledNodegroup.ScalingConfig = &types.NodegroupScalingConfig{
+		MinSize:     aws.Int32(1),
+		MaxSize:     aws.Int32(5),
+		DesiredSize: aws.Int32(4),
+	}
+
+	var original string = sourceHash(nodegroupSource(nodegroup), asgSource(group))
+	if got := sourceHash(nodegroupSource(&scaledNodegroup), asgSource(&scaled)); got != original {
+		t.Errorf("sourceHash(...): scaling changed the hash from %q to %q", original, got)
+	}
+
+	if got := sourceHash(nodegroupSource(nodegroup), asgSource(&resized)); got == original {
+		t.Errorf("sourceHash(...): changing the maximum size kept the hash %q", got)
+	}
+
+	if len(group.Instances) != 1 || aws.ToInt32(nodegroup.ScalingConfig.DesiredSize) != 1 {
+		t.Errorf("asgSource(...), nodegroupSource(...): modified the AWS API output")
+	}
+}
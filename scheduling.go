@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+// taintEffects maps the EKS taint effect enum onto the values CAPA expects
+var taintEffects map[types.TaintEffect]expinfrav2.TaintEffect = map[types.TaintEffect]expinfrav2.TaintEffect{
+	types.TaintEffectNoSchedule:       expinfrav2.TaintEffectNoSchedule,
+	types.TaintEffectNoExecute:        expinfrav2.TaintEffectNoExecute,
+	types.TaintEffectPreferNoSchedule: expinfrav2.TaintEffectPreferNoSchedule,
+}
+
+// mapTaints converts EKS nodegroup taints to CAPA taints
+//
+// Taints without a key or with an unknown effect cannot be represented and
+// are dropped with a warning. A missing value is treated as empty.
+func mapTaints(taints []types.Taint) (mapped expinfrav2.Taints, warnings []error) {
+	for _, taint := range taints {
+		if taint.Key == nil || *taint.Key == "" {
+			warnings = append(warnings, fmt.Errorf("dropping taint without a key"))
+			continue
+		}
+
+		effect, ok := taintEffects[taint.Effect]
+		if !ok {
+			warnings = append(warnings, fmt.Errorf("dropping taint %q with unknown effect %q", *taint.Key, taint.Effect))
+			continue
+		}
+
+		mapped = append(mapped, expinfrav2.Taint{
+			Effect: effect,
+			Key:    *taint.Key,
+			Value:  aws.ToString(taint.Value),
+		})
+	}
+	return
+}
+
+// validateLabels returns the labels which are valid Kubernetes labels along
+// with a warning for each label that is not
+func validateLabels(labels map[string]string) (valid map[string]string, warnings []error) {
+	if labels == nil {
+		return nil, nil
+	}
+
+	var keys []string = make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	valid = make(map[string]string, len(labels))
+	for _, k := range keys {
+		var v string = labels[k]
+		var problems []string = validation.IsQualifiedName(k)
+		for _, p := range validation.IsValidLabelValue(v) {
+			problems = append(problems, "value "+p)
+		}
+
+		if len(problems) > 0 {
+			warnings = append(warnings, fmt.Errorf("dropping invalid label %q: %s", k, strings.Join(problems, ", ")))
+			continue
+		}
+		valid[k] = v
+	}
+	return
+}
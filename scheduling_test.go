@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/google/go-cmp/cmp"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+func TestMapTaints(t *testing.T) {
+	cases := map[string]struct {
+		taints       []types.Taint
+		want         expinfrav2.Taints
+		wantWarnings int
+	}{
+		"no schedule": {
+			taints: []types.Taint{
+				{Effect: types.TaintEffectNoSchedule, Key: aws.String("dedicated"), Value: aws.String("gpu")},
+			},
+			want: expinfrav2.Taints{
+				{Effect: expinfrav2.TaintEffectNoSchedule, Key: "dedicated", Value: "gpu"},
+			},
+		},
+		"no execute": {
+			taints: []types.Taint{
+				{Effect: types.TaintEffectNoExecute, Key: aws.String("dedicated"), Value: aws.String("gpu")},
+			},
+			want: expinfrav2.Taints{
+				{Effect: expinfrav2.TaintEffectNoExecute, Key: "dedicated", Value: "gpu"},
+			},
+		},
+		"prefer no schedule": {
+			taints: []types.Taint{
+				{Effect: types.TaintEffectPreferNoSchedule, Key: aws.String("dedicated"), Value: aws.String("gpu")},
+			},
+			want: expinfrav2.Taints{
+				{Effect: expinfrav2.TaintEffectPreferNoSchedule, Key: "dedicated", Value: "gpu"},
+			},
+		},
+		"nil value": {
+			taints: []types.Taint{
+				{Effect: types.TaintEffectNoSchedule, Key: aws.String("spot")},
+			},
+			want: expinfrav2.Taints{
+				{Effect: expinfrav2.TaintEffectNoSchedule, Key: "spot", Value: ""},
+			},
+		},
+		"nil key": {
+			taints: []types.Taint{
+				{Effect: types.TaintEffectNoSchedule, Value: aws.String("gpu")},
+			},
+			wantWarnings: 1,
+		},
+		"unknown effect": {
+			taints: []types.Taint{
+				{Effect: types.TaintEffect("NO_WAY"), Key: aws.String("dedicated")},
+				{Effect: types.TaintEffectNoExecute, Key: aws.String("spot")},
+			},
+			want: expinfrav2.Taints{
+				{Effect: expinfrav2.TaintEffectNoExecute, Key: "spot", Value: ""},
+			},
+			wantWarnings: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, warnings := mapTaints(tc.taints)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mapTaints(...): -want, +got:\n%s", diff)
+			}
+
+			if len(warnings) != tc.wantWarnings {
+				t.Errorf("mapTaints(...): want %d warnings, got %v", tc.wantWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	got, warnings := validateLabels(map[string]string{
+		"role":                    "worker",
+		"example.com/team":        "honeybadger",
+		"bad key!":                "value",
+		"example.com/bad-value":   "not valid!",
+		"example.com/empty-value": "",
+	})
+
+	want := map[string]string{
+		"role":                    "worker",
+		"example.com/team":        "honeybadger",
+		"example.com/empty-value": "",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("validateLabels(...): -want, +got:\n%s", diff)
+	}
+
+	if len(warnings) != 2 {
+		t.Errorf("validateLabels(...): want 2 warnings, got %v", warnings)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	asg "github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+	capiinfra "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+const (
+	// clusterTagPrefix is the prefix of the tag marking resources which belong
+	// to a kubernetes cluster
+	clusterTagPrefix = "kubernetes.io/cluster/"
+
+	// ownedTagValue is the value of the cluster tag on resources owned by the
+	// cluster
+	ownedTagValue = "owned"
+
+	// nodegroupNameTag is set by EKS on the autoscaling groups it creates for
+	// managed nodegroups
+	nodegroupNameTag = "eks:nodegroup-name"
+)
+
+// importAutoscalingGroups discovers the self-managed autoscaling groups owned
+// by the cluster and adds an AWSMachinePool and MachinePool for each
+func (f *Function) importAutoscalingGroups(ac *XrConfig, ec2client AwsEc2Api, asgclient AwsAsgApi, filter *tagFilter, selection *nodegroupFilter) (err error) {
+	var groups []asgtypes.AutoScalingGroup
+	if groups, err = getOwnedAutoscalingGroups(*ac.cluster, asgclient); err != nil {
+		ac.warnings = append(ac.warnings, errors.Wrapf(err, "cannot list autoscaling groups for cluster %q, self-managed groups are not imported", *ac.cluster))
+		return nil
+	}
+
+	for i := range groups {
+		var (
+			group  *asgtypes.AutoScalingGroup = &groups[i]
+			name   string                     = aws.ToString(group.AutoScalingGroupName)
+			config *AutoscalingGroupConfig
+		)
+
+		if !selection.allowed(autoscalingGroupTarget(group)) {
+			f.log.Debug("AWSAPI", "skipping autoscaling group excluded by the input", name, "cluster", *ac.cluster)
+			continue
+		}
+
+		if config, err = autoscalingGroupToCapiObject(group, ec2client, filter); err != nil {
+			ac.warnings = append(ac.warnings, errors.Wrapf(err, "autoscaling group %q cannot be imported", name))
+			continue
+		}
+
+		var override *v1beta1.NodegroupOverride = ac.overrides.get(name)
+		if warning := applyLaunchTemplateOverride(override, &config.spec.AWSLaunchTemplate, config.annotations); warning != nil {
+			config.warnings = append(config.warnings, warning)
+		}
+
+		for _, warning := range config.warnings {
+			ac.warnings = append(ac.warnings, errors.Wrapf(warning, "autoscaling group %q", name))
+		}
+
+		var (
+			poolName string     = objectName(ac, "awsmachinepool", name)
+			source   provenance = autoscalingGroupProvenance(group, config)
+		)
+		f.log.Info("AWSAPI", "Creating machinepool", poolName)
+
+		var annotations map[string]string = newAnnotations(ac, "AWSMachinePool")
+		for k, v := range config.annotations {
+			annotations[k] = v
+		}
+
+		var awsmp expinfrav2.AWSMachinePool = expinfrav2.AWSMachinePool{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "AWSMachinePool",
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        poolName,
+				Namespace:   *ac.namespace,
+				Labels:      newLabels(ac, "AWSMachinePool", name),
+				Annotations: annotations,
+			},
+			Spec: *config.spec,
+			Status: expinfrav2.AWSMachinePoolStatus{
+				Ready:                 true,
+				Replicas:              int32(len(config.spec.ProviderIDList)),
+				LaunchTemplateID:      config.launchTemplateID,
+				LaunchTemplateVersion: config.launchTemplateVersion,
+			},
+		}
+
+		var eksconfig, bootstrap = newEKSConfig(ac, name, config.bootstrap)
+		var machinepool *capiinfra.MachineDeployment = newMachinePool(ac, name, group.DesiredCapacity,
+			config.version, bootstrap, v1.ObjectReference{
+				Kind:       "AWSMachinePool",
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta2",
+				Namespace:  *ac.namespace,
+				Name:       poolName,
+			})
+
+		if ac.input.ClusterAutoscaler {
+			setAutoscalerBounds(ac, machinepool, name, config.spec.MinSize, config.spec.MaxSize)
+		}
+
+		setDeletionPolicy(ac, override, poolName, machinepool.Name)
+		if eksconfig != nil {
+			setDeletionPolicy(ac, override, eksconfig.Name)
+			if err = f.addDesired(ac, eksconfig.Name, eksconfig, source); err != nil {
+				continue
+			}
+		}
+
+		if err = f.addDesired(ac, poolName, awsmp, source); err != nil {
+			continue
+		}
+
+		if err = f.addDesired(ac, machinepool.Name, machinepool, source); err != nil {
+			continue
+		}
+	}
+	return nil
+}
+
+// getOwnedAutoscalingGroups lists the autoscaling groups tagged as owned by
+// the cluster, leaving out those which belong to EKS managed nodegroups
+func getOwnedAutoscalingGroups(cluster string, client AwsAsgApi) (groups []asgtypes.AutoScalingGroup, err error) {
+	var input *asg.DescribeAutoScalingGroupsInput = &asg.DescribeAutoScalingGroupsInput{
+		Filters: []asgtypes.Filter{
+			{
+				Name:   aws.String("tag:" + clusterTagPrefix + cluster),
+				Values: []string{ownedTagValue},
+			},
+		},
+	}
+
+	for {
+		var res *asg.DescribeAutoScalingGroupsOutput
+		if res, err = GetAutoScalingGroups(context.TODO(), client, input); err != nil {
+			return nil, err
+		}
+
+		for _, group := range res.AutoScalingGroups {
+			if !hasTag(group.Tags, nodegroupNameTag) {
+				groups = append(groups, group)
+			}
+		}
+
+		if res.NextToken == nil {
+			return groups, nil
+		}
+		input.NextToken = res.NextToken
+	}
+}
+
+// autoscalingGroupToCapiObject pulls together the information required to
+// create an AWSMachinePool for a self-managed autoscaling group
+func autoscalingGroupToCapiObject(group *asgtypes.AutoScalingGroup, ec2client AwsEc2Api, filter *tagFilter) (config *AutoscalingGroupConfig, err error) {
+	var spec *expinfrav2.AWSMachinePoolSpec = &expinfrav2.AWSMachinePoolSpec{
+		MinSize:           aws.ToInt32(group.MinSize),
+		MaxSize:           aws.ToInt32(group.MaxSize),
+		AvailabilityZones: group.AvailabilityZones,
+		CapacityRebalance: aws.ToBool(group.CapacityRebalance),
+	}
+	config = &AutoscalingGroupConfig{
+		spec:        spec,
+		annotations: make(map[string]string),
+	}
+
+	var reference *asgtypes.LaunchTemplateSpecification = group.LaunchTemplate
+	if reference == nil && group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil {
+		reference = group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+
+	if reference == nil {
+		return nil, fmt.Errorf("launch configurations are not supported by CAPA")
+	}
+
+	// Without a version the autoscaling group uses the default version
+	var version string = "$Default"
+	if reference.Version != nil {
+		version = *reference.Version
+	}
+
+	var launchTemplate *LaunchTemplateConfig
+	if launchTemplate, err = getLaunchTemplate(&types.LaunchTemplateSpecification{
+		Id:      reference.LaunchTemplateId,
+		Name:    reference.LaunchTemplateName,
+		Version: &version,
+	}, ec2client); err != nil {
+		return nil, errors.Wrap(err, "cannot describe launch template")
+	}
+
+	config.sources = []any{asgSource(group), launchTemplate.data}
+	spec.AWSLaunchTemplate = *launchTemplate.template
+	config.launchTemplateID = launchTemplate.id
+	if launchTemplate.template.VersionNumber != nil {
+		var number string = strconv.FormatInt(*launchTemplate.template.VersionNumber, 10)
+		config.launchTemplateVersion = &number
+	}
+
+	for k, v := range launchTemplate.annotations {
+		config.annotations[k] = v
+	}
+
+	image, annotations, warnings := resolveAMI(&spec.AWSLaunchTemplate, ec2client)
+	for k, v := range annotations {
+		config.annotations[k] = v
+	}
+	config.warnings = append(config.warnings, warnings...)
+
+	// Self-managed groups carry no Kubernetes version other than the one the
+	// AMI was built for
+	if image.kubernetesVersion != "" {
+		var version string = "v" + image.kubernetesVersion
+		config.version = &version
+	}
+
+	if launchTemplate.userData != nil {
+		var warnings []error
+		if config.bootstrap, warnings, err = parseUserData(*launchTemplate.userData, image.family, config.annotations); err != nil {
+			config.warnings = append(config.warnings, errors.Wrap(err, "cannot import bootstrap configuration"))
+		}
+		config.warnings = append(config.warnings, warnings...)
+	}
+
+	var tags map[string]string = make(map[string]string)
+	filter.merge(tags, asgTags(group.Tags))
+	filter.merge(tags, launchTemplate.tags)
+	if len(tags) > 0 {
+		spec.AdditionalTags = infrav2.Tags(tags)
+	}
+
+	for _, subnet := range strings.Split(aws.ToString(group.VPCZoneIdentifier), ",") {
+		var id string = strings.TrimSpace(subnet)
+		if id != "" {
+			spec.Subnets = append(spec.Subnets, infrav2.AWSResourceReference{ID: &id})
+		}
+	}
+
+	if group.DefaultCooldown != nil {
+		spec.DefaultCoolDown = metav1.Duration{Duration: time.Duration(*group.DefaultCooldown) * time.Second}
+	}
+
+	spec.MixedInstancesPolicy = mixedInstancesPolicy(group.MixedInstancesPolicy)
+
+	for _, instance := range group.Instances {
+		var pid string = fmt.Sprintf("aws:///%s/%s", *instance.AvailabilityZone, *instance.InstanceId)
+		spec.ProviderIDList = append(spec.ProviderIDList, pid)
+	}
+	return config, nil
+}
+
+// mixedInstancesPolicy converts the instance type overrides and the instance
+// distribution of an autoscaling group into the CAPA form
+func mixedInstancesPolicy(policy *asgtypes.MixedInstancesPolicy) *expinfrav2.MixedInstancesPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	var mixed *expinfrav2.MixedInstancesPolicy = &expinfrav2.MixedInstancesPolicy{}
+	if policy.LaunchTemplate != nil {
+		for _, override := range policy.LaunchTemplate.Overrides {
+			if override.InstanceType != nil {
+				mixed.Overrides = append(mixed.Overrides, expinfrav2.Overrides{
+					InstanceType: *override.InstanceType,
+				})
+			}
+		}
+	}
+
+	if d := policy.InstancesDistribution; d != nil {
+		mixed.InstancesDistribution = &expinfrav2.InstancesDistribution{
+			OnDemandAllocationStrategy:          expinfrav2.OnDemandAllocationStrategy(aws.ToString(d.OnDemandAllocationStrategy)),
+			SpotAllocationStrategy:              expinfrav2.SpotAllocationStrategy(aws.ToString(d.SpotAllocationStrategy)),
+			OnDemandBaseCapacity:                toInt64(d.OnDemandBaseCapacity),
+			OnDemandPercentageAboveBaseCapacity: toInt64(d.OnDemandPercentageAboveBaseCapacity),
+		}
+	}
+	return mixed
+}
+
+// hasTag returns true if the tags contain the given key
+func hasTag(tags []asgtypes.TagDescription, key string) bool {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func toInt64(v *int32) *int64 {
+	if v == nil {
+		return nil
+	}
+
+	var i int64 = int64(*v)
+	return &i
+}
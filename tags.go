@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	asgtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
+)
+
+// managedTagPrefixes are the prefixes of tag keys owned by AWS, EKS or the
+// cloud provider integration. These tags are recreated by AWS and must not be
+// carried over into CAPI.
+var managedTagPrefixes = []string{
+	"aws:",
+	"eks:",
+	"kubernetes.io/cluster/",
+}
+
+// tagFilter decides which AWS tags are imported into `additionalTags`
+type tagFilter struct {
+	include, exclude []*regexp.Regexp
+}
+
+// newTagFilter compiles the include and exclude patterns given in the input
+func newTagFilter(spec *v1beta1.TagFilter) (filter *tagFilter, err error) {
+	filter = &tagFilter{}
+	if spec == nil {
+		return
+	}
+
+	if filter.include, err = compilePatterns(spec.Include); err != nil {
+		return nil, errors.Wrap(err, "invalid tag include pattern")
+	}
+
+	if filter.exclude, err = compilePatterns(spec.Exclude); err != nil {
+		return nil, errors.Wrap(err, "invalid tag exclude pattern")
+	}
+	return
+}
+
+// allowed returns true if a tag with the given key should be imported
+func (t *tagFilter) allowed(key string) bool {
+	for _, prefix := range managedTagPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+
+	for _, re := range t.exclude {
+		if re.MatchString(key) {
+			return false
+		}
+	}
+
+	if len(t.include) == 0 {
+		return true
+	}
+
+	for _, re := range t.include {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge copies all allowed tags from `tags` into `into`
+func (t *tagFilter) merge(into, tags map[string]string) {
+	for k, v := range tags {
+		if t.allowed(k) {
+			into[k] = v
+		}
+	}
+}
+
+// asgTags returns the tags of the autoscaling group which are propagated to
+// the instances it launches
+func asgTags(tags []asgtypes.TagDescription) map[string]string {
+	var propagated map[string]string = make(map[string]string)
+	for _, tag := range tags {
+		if aws.ToBool(tag.PropagateAtLaunch) {
+			propagated[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return propagated
+}
+
+func compilePatterns(patterns []string) (compiled []*regexp.Regexp, err error) {
+	for _, pattern := range patterns {
+		var re *regexp.Regexp
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"text/template"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	fnv1beta1 "github.com/crossplane/function-sdk-go/proto/v1beta1"
+	"github.com/crossplane/function-sdk-go/resource/composed"
 
 	"github.com/giantswarm/xfnlib/pkg/composite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eksbootstrapv1 "sigs.k8s.io/cluster-api-provider-aws/v2/bootstrap/eks/api/v1beta2"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
 
-	xfc "github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/composite/v1beta1"
+	"github.com/giantswarm/crossplane-fn-describe-nodegroups/pkg/input/v1beta1"
 )
 
 // Policy Policies for referencing.
@@ -27,21 +33,79 @@ type ConnectionSecretRef struct {
 	Namespace string `json:"namespace"`
 }
 
-// EksImportXRObject is the information we are going to pull from the XR
+// EksImportXRObject is the information we are going to pull from the XR.
+// Everything else is read through the field paths given in the input so that
+// any XR schema can be used.
 type EksImportXRObject struct {
 	Metadata metav1.ObjectMeta `json:"metadata"`
-	Spec     xfc.XrSpec        `json:"spec"`
 }
 
 type XrConfig struct {
 	cluster, namespace, region, providerConfigRef *string
-	labels, annotations                           map[string]string
+	clusterProviderConfigRef, deletionPolicy      string
+	labels, annotations, additionalLabels         map[string]string
 	composed                                      *composite.Composition
 	composite                                     EksImportXRObject
+	input                                         *v1beta1.Spec
+	observedCluster                               *composed.Unstructured
+	policy                                        *metadataPolicy
+	nameTemplate                                  *template.Template
+	names                                         map[string]string
+	autoscalerNodeGroups                          []autoscalerNodeGroup
+	overrides                                     *nodegroupOverrides
+	deletionPolicies                              map[string]string
+	patches                                       []objectPatch
+	warnings                                      []error
+}
+
+// NodegroupConfig holds the CAPA spec built for a single nodegroup along with
+// any information discovered for it that the spec cannot hold
+type NodegroupConfig struct {
+	spec        *expinfrav2.AWSManagedMachinePoolSpec
+	bootstrap   *eksbootstrapv1.EKSConfigSpec
+	family      amiFamily
+	annotations map[string]string
+	warnings    []error
+
+	// windowsRelease is the Windows Server release of Windows nodegroups
+	windowsRelease string
+
+	// sources is the AWS API output the pool was mapped from
+	sources []any
+}
+
+// AutoscalingGroupConfig holds the CAPA spec built for a self-managed
+// autoscaling group along with any information discovered for it that the
+// spec cannot hold
+type AutoscalingGroupConfig struct {
+	spec                  *expinfrav2.AWSMachinePoolSpec
+	bootstrap             *eksbootstrapv1.EKSConfigSpec
+	version               *string
+	launchTemplateID      string
+	launchTemplateVersion *string
+	annotations           map[string]string
+	warnings              []error
+
+	// sources is the AWS API output the pool was mapped from
+	sources []any
+}
+
+// LaunchTemplateConfig holds the CAPA launch template built from an EC2 launch
+// template version, and the settings of that version CAPA has no field for
+type LaunchTemplateConfig struct {
+	id          string
+	template    *expinfrav2.AWSLaunchTemplate
+	userData    *string
+	tags        map[string]string
+	annotations map[string]string
+
+	// data is the launch template version as returned by EC2
+	data *ec2types.ResponseLaunchTemplateData
 }
 
 // Function returns whatever response you ask it to.
 type Function struct {
 	fnv1beta1.UnimplementedFunctionRunnerServiceServer
-	log logging.Logger
+	log              logging.Logger
+	defaultNamespace string
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"k8s.io/apimachinery/pkg/util/version"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+// controlPlaneVersionPaths are the fields on the observed cluster resource
+// read for the control plane version, in order of preference
+var controlPlaneVersionPaths = []string{
+	"status.atProvider.version",
+	"spec.forProvider.version",
+}
+
+// kubernetesVersion returns the Kubernetes version of the nodegroup in the
+// form expected by CAPI. Where the release version carries the patch level
+// this is included.
+func kubernetesVersion(group *types.Nodegroup) *string {
+	if group.Version == nil || *group.Version == "" {
+		return nil
+	}
+
+	var v string = *group.Version
+	if release := aws.ToString(group.ReleaseVersion); strings.HasPrefix(release, v+".") {
+		v = strings.SplitN(release, "-", 2)[0]
+	}
+
+	v = "v" + v
+	return &v
+}
+
+// amiVersion returns the AMI release version of the nodegroup for the CAPA
+// AMI type it was mapped to
+//
+// For custom AMIs EKS reports the AMI ID as the release version, which is
+// already held on the launch template. AMI types CAPA cannot hold leave the
+// pool on AL2_x86_64, so the release of a Bottlerocket or AL2023 nodegroup
+// would select an AL2 image of a version which may not exist.
+func amiVersion(group *types.Nodegroup, amiType *expinfrav2.ManagedMachineAMIType) *string {
+	var release string = aws.ToString(group.ReleaseVersion)
+	if amiType == nil || release == "" || strings.HasPrefix(release, "ami-") {
+		return nil
+	}
+	return &release
+}
+
+// controlPlaneVersion reads the Kubernetes version of the control plane from
+// the observed cluster resource
+func controlPlaneVersion(cluster *composed.Unstructured) string {
+	if cluster == nil {
+		return ""
+	}
+
+	for _, path := range controlPlaneVersionPaths {
+		if v, err := cluster.GetString(path); err == nil && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// maxVersionSkew returns how many minor versions a kubelet may lag behind the
+// control plane. From Kubernetes 1.28 this was raised from two to three.
+func maxVersionSkew(controlPlane *version.Version) uint {
+	if controlPlane.Minor() >= 28 {
+		return 3
+	}
+	return 2
+}
+
+// checkVersionSkew returns an error if the nodegroup version falls outside of
+// the supported skew from the control plane
+func checkVersionSkew(controlPlane, nodegroup string) (err error) {
+	var cp, ng *version.Version
+	if cp, err = version.ParseGeneric(controlPlane); err != nil {
+		return errors.Wrapf(err, "cannot parse control plane version %q", controlPlane)
+	}
+
+	if ng, err = version.ParseGeneric(nodegroup); err != nil {
+		return errors.Wrapf(err, "cannot parse nodegroup version %q", nodegroup)
+	}
+
+	if cp.Major() != ng.Major() {
+		return fmt.Errorf("nodegroup version %s does not match control plane major version %s", nodegroup, controlPlane)
+	}
+
+	if ng.Minor() > cp.Minor() {
+		return fmt.Errorf("nodegroup version %s is newer than control plane version %s", nodegroup, controlPlane)
+	}
+
+	if skew := cp.Minor() - ng.Minor(); skew > maxVersionSkew(cp) {
+		return fmt.Errorf("nodegroup version %s is %d minor versions behind control plane version %s, the supported maximum is %d", nodegroup, skew, controlPlane, maxVersionSkew(cp))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/google/go-cmp/cmp"
+	expinfrav2 "sigs.k8s.io/cluster-api-provider-aws/v2/exp/api/v1beta2"
+)
+
+func TestKubernetesVersion(t *testing.T) {
+	cases := map[string]struct {
+		group *types.Nodegroup
+		want  *string
+	}{
+		"no version": {
+			group: &types.Nodegroup{},
+		},
+		"minor version only": {
+			group: &types.Nodegroup{Version: aws.String("1.27")},
+			want:  aws.String("v1.27"),
+		},
+		"patch version from release": {
+			group: &types.Nodegroup{
+				Version:        aws.String("1.27"),
+				ReleaseVersion: aws.String("1.27.9-20240202"),
+			},
+			want: aws.String("v1.27.9"),
+		},
+		"custom AMI release": {
+			group: &types.Nodegroup{
+				Version:        aws.String("1.27"),
+				ReleaseVersion: aws.String("ami-0123456789abcdef0"),
+			},
+			want: aws.String("v1.27"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, kubernetesVersion(tc.group)); diff != "" {
+				t.Errorf("kubernetesVersion(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAMIVersion(t *testing.T) {
+	var al2 expinfrav2.ManagedMachineAMIType = expinfrav2.Al2x86_64
+
+	cases := map[string]struct {
+		group   *types.Nodegroup
+		amiType *expinfrav2.ManagedMachineAMIType
+		want    *string
+	}{
+		"no release": {
+			group:   &types.Nodegroup{},
+			amiType: &al2,
+		},
+		"release of a mapped AMI type": {
+			group:   &types.Nodegroup{ReleaseVersion: aws.String("1.27.9-20240202")},
+			amiType: &al2,
+			want:    aws.String("1.27.9-20240202"),
+		},
+		"release of an AMI type CAPA cannot hold": {
+			group: &types.Nodegroup{
+				AmiType:        types.AMITypesBottlerocketX8664,
+				ReleaseVersion: aws.String("1.19.0-0d9d9e5f"),
+			},
+		},
+		"custom AMI release": {
+			group:   &types.Nodegroup{ReleaseVersion: aws.String("ami-0123456789abcdef0")},
+			amiType: &al2,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, amiVersion(tc.group, tc.amiType)); diff != "" {
+				t.Errorf("amiVersion(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	cases := map[string]struct {
+		controlPlane, nodegroup string
+		wantErr                 bool
+	}{
+		"same version":           {controlPlane: "1.27", nodegroup: "1.27"},
+		"two behind before 1.28": {controlPlane: "1.27", nodegroup: "1.25"},
+		"three behind before 1.28": {
+			controlPlane: "1.27", nodegroup: "1.24", wantErr: true,
+		},
+		"three behind from 1.28": {controlPlane: "1.29", nodegroup: "1.26"},
+		"four behind from 1.28": {
+			controlPlane: "1.29", nodegroup: "1.25", wantErr: true,
+		},
+		"newer than control plane": {
+			controlPlane: "1.27", nodegroup: "1.28", wantErr: true,
+		},
+		"unparseable": {
+			controlPlane: "latest", nodegroup: "1.28", wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkVersionSkew(tc.controlPlane, tc.nodegroup)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("checkVersionSkew(%q, %q): unexpected error state: %v", tc.controlPlane, tc.nodegroup, err)
+			}
+		})
+	}
+}